@@ -0,0 +1,374 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+/*
+	Package banburismus finds the right rotor of naval messages the way Hut 8
+	did, by sliding messages over each other and scoring the repeats.
+
+	Two messages whose settings only differ in the right rotor are in depth
+	once the first has caught up with the second: from then on both are
+	enciphered at the same positions, so a letter repeats between them as
+	often as it does between two plaintexts, about 1 in 13 for German,
+	rather than 1 in 26. That holds unless the right rotor passes its
+	turnover between the two settings, which moves the first message's
+	middle rotor on. So the pairs that are in depth, and those that are not,
+	tell where the turnover of the right rotor is, and with it which rotor
+	it is.
+*/
+package banburismus
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	enigma "github.com/mww/enigma-go"
+	"github.com/mww/enigma-go/frequency"
+)
+
+/*
+	A message and the rotor windows it was enciphered from. Setting is the
+	true message setting, e.g. "VFG", not the indicator as it was sent, so
+	letters of it that are a few apart in the alphabet started a few steps
+	apart.
+*/
+type Message struct {
+	Setting string
+	Text    string
+}
+
+type Options struct {
+	/*
+		The language of the plaintexts. Defaults to german-x, which is an
+		estimate of German with X between words, so the weights are too.
+	*/
+	Language *frequency.Language
+
+	/*
+		The decibans a pair's best offset must score to count as in depth.
+		Defaults to 10, odds of 10 to 1 on, when nil.
+	*/
+	Threshold *float64
+
+	// The fewest letters two messages must overlap by to be scored. Defaults to 30.
+	MinOverlap int
+
+	// The rotors the right one is among, by name. Defaults to I-VIII.
+	Rotors []string
+}
+
+func (o *Options) setDefaults() {
+	if o.Language == nil {
+		o.Language, _ = frequency.LanguageByName("german-x")
+	}
+	if o.Threshold == nil {
+		threshold := 10.0
+		o.Threshold = &threshold
+	}
+	if o.MinOverlap <= 0 {
+		o.MinOverlap = 30
+	}
+	if len(o.Rotors) == 0 {
+		o.Rotors = []string{"I", "II", "III", "IV", "V", "VI", "VII", "VIII"}
+	}
+}
+
+/*
+	Weights are the decibans, tenths of a factor of 10 in the odds, that
+	each column of two overlapping messages adds to the case that they are
+	in depth.
+*/
+type Weights struct {
+	Repeat, Other float64
+}
+
+// NewWeights works out the weights from how often the language repeats letters.
+func NewWeights(l *frequency.Language) Weights {
+	p := l.IndexOfCoincidence()
+	return Weights{
+		Repeat: 10 * math.Log10(p*26),
+		Other:  10 * math.Log10((1-p)*26/25),
+	}
+}
+
+/*
+	Score overlaps b, starting offset letters after a, or before it when
+	offset is negative, and returns how many letters overlap, how many of
+	them repeat and the decibans they add up to.
+*/
+func Score(a, b string, offset int, w Weights) (overlap, repeats int, score float64) {
+	if offset < 0 {
+		return Score(b, a, -offset, w)
+	}
+	for i := 0; i+offset < len(a) && i < len(b); i++ {
+		overlap++
+		if a[i+offset] == b[i] {
+			repeats++
+		}
+	}
+	return overlap, repeats, float64(repeats)*w.Repeat + float64(overlap-repeats)*w.Other
+}
+
+/*
+	A Pair of messages whose settings only differ in the right rotor, at the
+	offset that scored best. B starts Offset letters after A, or before it
+	if Offset is negative.
+*/
+type Pair struct {
+	A, B             int // Indexes of the messages.
+	Offset           int
+	Overlap, Repeats int
+	Score            float64
+}
+
+/*
+	Pairs scores every offset of each pair of messages whose settings only
+	differ in the right rotor, up to a whole turn of it either way, and
+	returns each pair at its best offset, best first.
+*/
+func Pairs(messages []Message, opts Options) ([]Pair, error) {
+	opts.setDefaults()
+	if err := check(messages); err != nil {
+		return nil, err
+	}
+	w := NewWeights(opts.Language)
+	var pairs []Pair
+	for i := range messages {
+		for j := i + 1; j < len(messages); j++ {
+			a, b := messages[i], messages[j]
+			if a.Setting[:2] != b.Setting[:2] {
+				continue
+			}
+			best := Pair{A: i, B: j, Score: math.Inf(-1)}
+			for offset := -25; offset <= 25; offset++ {
+				overlap, repeats, score := Score(a.Text, b.Text, offset, w)
+				if overlap >= opts.MinOverlap && score > best.Score {
+					best.Offset, best.Overlap, best.Repeats, best.Score = offset, overlap, repeats, score
+				}
+			}
+			if best.Overlap > 0 {
+				pairs = append(pairs, best)
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Score > pairs[j].Score })
+	return pairs, nil
+}
+
+func check(messages []Message) error {
+	for i, m := range messages {
+		if len(m.Setting) != 3 || strings.IndexFunc(m.Setting+m.Text, notLetter) >= 0 {
+			return fmt.Errorf("message %d needs a setting of 3 letters and only the letters A-Z", i+1)
+		}
+	}
+	return nil
+}
+
+/*
+	Chains joins the right rotor letters of the pairs in depth by their
+	offsets, as Hut 8 did on paper, and writes each chain with a dash for
+	every position between its letters, e.g. "G--K-P", or "Y--B" where it
+	goes round past Z. Pairs whose offset disagrees with their settings, or
+	with the letters of a chain already built, are left out, as are pairs
+	that would make a chain go more than once round the alphabet.
+*/
+func Chains(messages []Message, pairs []Pair, threshold float64) []string {
+	// The chain of each letter and its position in the chain.
+	var chain, position [26]int
+	for i := range chain {
+		chain[i] = -1
+	}
+	next := 0
+	for _, p := range pairs {
+		if p.Score < threshold || !agrees(messages, p) {
+			continue
+		}
+		a, b := int(messages[p.A].Setting[2]-'A'), int(messages[p.B].Setting[2]-'A')
+		offset := p.Offset
+		if chain[a] < 0 && chain[b] < 0 {
+			chain[a], position[a] = next, 0
+			chain[b], position[b] = next, offset
+			next++
+			continue
+		}
+		if chain[a] < 0 {
+			a, b, offset = b, a, -offset
+		}
+		// Where b's letters go on a's chain. They can't contradict the
+		// letters already there, which all agree with their settings, unless
+		// the chain would then go round more than once.
+		from, shift := chain[b], position[a]+offset-position[b]
+		if from == chain[a] {
+			continue // Already joined, by this offset or the other way round.
+		}
+		lo, hi := position[a]+offset, position[a]+offset
+		for c := range chain {
+			if chain[c] == chain[a] || (from >= 0 && chain[c] == from) {
+				pos := position[c]
+				if chain[c] != chain[a] {
+					pos += shift
+				}
+				if pos < lo {
+					lo = pos
+				}
+				if pos > hi {
+					hi = pos
+				}
+			}
+		}
+		if hi-lo >= 26 {
+			continue
+		}
+		if from < 0 {
+			chain[b], position[b] = chain[a], position[a]+offset
+			continue
+		}
+		for c := range chain {
+			if chain[c] == from {
+				chain[c], position[c] = chain[a], position[c]+shift
+			}
+		}
+	}
+
+	var chains []string
+	for n := 0; n < next; n++ {
+		var letters []int
+		for c := range chain {
+			if chain[c] == n {
+				letters = append(letters, c)
+			}
+		}
+		if len(letters) == 0 {
+			continue
+		}
+		sort.Slice(letters, func(i, j int) bool { return position[letters[i]] < position[letters[j]] })
+		var buf strings.Builder
+		for i, c := range letters {
+			if i > 0 {
+				buf.WriteString(strings.Repeat("-", position[c]-position[letters[i-1]]-1))
+			}
+			buf.WriteRune(rune('A' + c))
+		}
+		chains = append(chains, buf.String())
+	}
+	return chains
+}
+
+/*
+	Turnovers returns the letters the right rotor could turn the middle one
+	over at. A pair in depth at an offset that agrees with its settings
+	shows that the rotor didn't turn over between them.
+*/
+func Turnovers(messages []Message, pairs []Pair, threshold float64) []rune {
+	var ruledOut [26]bool
+	for _, p := range pairs {
+		if p.Score < threshold || !agrees(messages, p) {
+			continue
+		}
+		first, steps := int(messages[p.A].Setting[2]-'A'), p.Offset
+		if steps < 0 {
+			first, steps = int(messages[p.B].Setting[2]-'A'), -steps
+		}
+		// The first message stepped from these letters before the second started.
+		for i := 0; i < steps; i++ {
+			ruledOut[(first+i)%26] = true
+		}
+	}
+	var letters []rune
+	for i, out := range ruledOut {
+		if !out {
+			letters = append(letters, rune('A'+i))
+		}
+	}
+	return letters
+}
+
+/*
+	Whether the pair's offset is the distance between the right rotor
+	letters of its settings. If not the repeats are by chance.
+*/
+func agrees(messages []Message, p Pair) bool {
+	a, b := int(messages[p.A].Setting[2]-'A'), int(messages[p.B].Setting[2]-'A')
+	return ((b-a-p.Offset)%26+26)%26 == 0
+}
+
+/*
+	Wheels returns the rotors whose turnovers are all among the letters.
+	Each one turns the middle rotor over as it steps on from one of them.
+*/
+func Wheels(turnovers []rune, rotors []string) ([]string, error) {
+	var possible [26]bool
+	for _, c := range turnovers {
+		possible[c-'A'] = true
+	}
+	var wheels []string
+	for _, name := range rotors {
+		r, err := enigma.RotorByName(name)
+		if err != nil {
+			return nil, err
+		}
+		fits := true
+		for c := int32(0); c < 26; c++ {
+			if r.Turnover((c+1)%26) && !possible[c] {
+				fits = false
+			}
+		}
+		if fits {
+			wheels = append(wheels, name)
+		}
+	}
+	return wheels, nil
+}
+
+// Everything Analyze finds out about a set of messages.
+type Report struct {
+	Pairs     []Pair
+	Chains    []string
+	Turnovers []rune
+	Wheels    []string
+}
+
+// Analyze scores the pairs of the messages and reports what they show.
+func Analyze(messages []Message, opts Options) (*Report, error) {
+	opts.setDefaults()
+	pairs, err := Pairs(messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	inDepth := 0
+	for _, p := range pairs {
+		if p.Score >= *opts.Threshold {
+			inDepth++
+		}
+	}
+	if inDepth == 0 {
+		return nil, errors.New("no pair of messages is in depth")
+	}
+	r := &Report{
+		Pairs:     pairs,
+		Chains:    Chains(messages, pairs, *opts.Threshold),
+		Turnovers: Turnovers(messages, pairs, *opts.Threshold),
+	}
+	if r.Wheels, err = Wheels(r.Turnovers, opts.Rotors); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func notLetter(r rune) bool {
+	return r < 'A' || r > 'Z'
+}
@@ -0,0 +1,214 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package banburismus
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	enigma "github.com/mww/enigma-go"
+	"github.com/mww/enigma-go/frequency"
+)
+
+func TestScore(t *testing.T) {
+	w := Weights{Repeat: 3, Other: -1}
+	overlap, repeats, score := Score("ABCDEF", "CXEX", 2, w)
+	if overlap != 4 || repeats != 2 || score != 4 {
+		t.Errorf("Expected 4 2 4, got %d %d %f", overlap, repeats, score)
+	}
+	overlap, repeats, score = Score("CXEX", "ABCDEF", -2, w)
+	if overlap != 4 || repeats != 2 || score != 4 {
+		t.Errorf("Expected 4 2 4, got %d %d %f", overlap, repeats, score)
+	}
+
+	german, _ := frequency.LanguageByName("german")
+	w = NewWeights(german)
+	if w.Repeat < 2.5 || w.Repeat > 3.5 || w.Other > 0 || w.Other < -0.3 {
+		t.Errorf("Expected about 3 and -0.2 decibans, got %+v", w)
+	}
+}
+
+// Random plaintexts with the letters of the language, enciphered from each setting.
+func messages(t *testing.T, k enigma.Key, settings []string, length int) []Message {
+	l, _ := frequency.LanguageByName("german-x")
+	r := rand.New(rand.NewSource(5))
+	var list []Message
+	for _, s := range settings {
+		text := make([]rune, length)
+		for i := range text {
+			x := r.Float64() * 100
+			for c := 'A'; c <= 'Z'; c++ {
+				if x -= l.Frequencies[c]; x < 0 || c == 'Z' {
+					text[i] = c
+					break
+				}
+			}
+		}
+		k.Positions = s
+		m, err := k.NewMachine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		list = append(list, Message{s, m.Encrypt(string(text))})
+	}
+	return list
+}
+
+func TestAnalyze(t *testing.T) {
+	k := enigma.Key{Reflector: "B", Rotors: []string{"II", "IV", "I"}, Plugboard: "AQ BJ CX EM HT LZ"}
+	settings := []string{"VFA", "VFC", "VFF", "VFH", "VFK", "VFM", "VFP", "VFS", "VFT", "VFW", "KMB"}
+	r, err := Analyze(messages(t, k, settings, 250), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Wheels, []string{"I"}) {
+		t.Errorf("Expected I, got %v from the turnovers %s", r.Wheels, string(r.Turnovers))
+	}
+	// No message started between P and S, where the turnover at Q is.
+	if s := string(r.Turnovers); s != "PQR" {
+		t.Errorf("Expected PQR, got %s", s)
+	}
+
+	// Apart from the turnover the offsets agree with the settings.
+	if len(r.Chains) != 1 {
+		t.Fatalf("Expected one chain, got %v", r.Chains)
+	}
+	chain := r.Chains[0]
+	for i := range chain {
+		for j := i + 1; j < len(chain); j++ {
+			if chain[i] != '-' && chain[j] != '-' && (j-i-int(chain[j])+int(chain[i]))%26 != 0 {
+				t.Errorf("Expected %c and %c to be %d apart in %s", chain[i], chain[j], j-i, chain)
+			}
+		}
+	}
+	for _, p := range r.Pairs {
+		if settings[p.A][:2] != settings[p.B][:2] {
+			t.Errorf("Expected only pairs with the same left and middle rotors, got %+v", p)
+		}
+	}
+
+	if _, err := Analyze([]Message{{"VFA", "ABC"}, {"VF", "ABC"}}, Options{}); err == nil {
+		t.Errorf("Expected a setting of 2 letters to fail")
+	}
+	if _, err := Analyze(messages(t, k, []string{"VFA", "KMB"}, 100), Options{}); err == nil {
+		t.Errorf("Expected no messages in depth to fail")
+	}
+}
+
+func TestChains(t *testing.T) {
+	ms := []Message{{"AAB", ""}, {"AAE", ""}, {"AAD", ""}, {"AAX", ""}, {"AAY", ""}}
+	pairs := []Pair{
+		{A: 0, B: 1, Offset: 3, Score: 20},
+		{A: 3, B: 4, Offset: 1, Score: 20},
+		{A: 1, B: 2, Offset: -1, Score: 20},
+		{A: 0, B: 2, Offset: 5, Score: 20}, // Disagrees with the settings.
+		{A: 2, B: 3, Offset: -6, Score: 5}, // Too weak.
+	}
+	if c := Chains(ms, pairs, 10); !reflect.DeepEqual(c, []string{"B-DE", "XY"}) {
+		t.Errorf("Expected [B-DE XY], got %v", c)
+	}
+	// X starts 6 letters before D, going round past Z.
+	pairs[4].Score = 20
+	if c := Chains(ms, pairs, 10); !reflect.DeepEqual(c, []string{"XY--B-DE"}) {
+		t.Errorf("Expected [XY--B-DE], got %v", c)
+	}
+	// D 24 letters before B agrees with the settings but not the chain.
+	more := append(pairs, Pair{A: 0, B: 2, Offset: -24, Score: 20})
+	if c := Chains(ms, more, 10); !reflect.DeepEqual(c, []string{"XY--B-DE"}) {
+		t.Errorf("Expected [XY--B-DE], got %v", c)
+	}
+
+	// E after D contradicts D after E.
+	contradicts := append(pairs, Pair{A: 2, B: 1, Offset: -25, Score: 20})
+	if c := Chains(ms, contradicts, 10); !reflect.DeepEqual(c, []string{"XY--B-DE"}) {
+		t.Errorf("Expected [XY--B-DE], got %v", c)
+	}
+
+	// The rotor stepped from X to D without turning over, if X and D are in depth.
+	if tu := string(Turnovers(ms, pairs, 10)); tu != "EFGHIJKLMNOPQRSTUVW" {
+		t.Errorf("Expected X-D to be ruled out, got %s", tu)
+	}
+	pairs[4].Score = 5
+	if tu := string(Turnovers(ms, pairs, 10)); tu != "AEFGHIJKLMNOPQRSTUVWYZ" {
+		t.Errorf("Expected B, C, D and X to be ruled out, got %s", tu)
+	}
+	if tu := string(Turnovers(ms, pairs, 0)); tu != "EFGHIJKLMNOPQRSTUVW" {
+		t.Errorf("Expected X-D to be ruled out at a threshold of 0, got %s", tu)
+	}
+}
+
+func TestChainsGoRoundOnce(t *testing.T) {
+	ms := []Message{{"AAA", ""}, {"AAN", ""}, {"AAZ", ""}, {"AAB", ""}, {"AAC", ""}}
+	pairs := []Pair{
+		{A: 0, B: 1, Offset: 13, Score: 20},
+		{A: 1, B: 2, Offset: 12, Score: 20},
+		{A: 2, B: 0, Offset: 1, Score: 20}, // A is already 25 before Z.
+		{A: 2, B: 3, Offset: 2, Score: 20}, // B would be 27 after A.
+		{A: 3, B: 4, Offset: 1, Score: 20},
+		{A: 4, B: 2, Offset: -3, Score: 20}, // BC would be 26 after A.
+	}
+	expected := []string{"A------------N-----------Z", "BC"}
+	if c := Chains(ms, pairs, 10); !reflect.DeepEqual(c, expected) {
+		t.Errorf("Expected %v, got %v", expected, c)
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	var o Options
+	o.setDefaults()
+	if *o.Threshold != 10 {
+		t.Errorf("Expected 10, got %f", *o.Threshold)
+	}
+	zero := 0.0
+	o = Options{Threshold: &zero}
+	o.setDefaults()
+	if *o.Threshold != 0 {
+		t.Errorf("Expected 0, got %f", *o.Threshold)
+	}
+
+	k := enigma.Key{Reflector: "B", Rotors: []string{"II", "IV", "I"}}
+	high := 1000.0
+	if _, err := Analyze(messages(t, k, []string{"VFA", "VFC"}, 250), Options{Threshold: &high}); err == nil {
+		t.Errorf("Expected no pair to be in depth at 1000 decibans")
+	}
+}
+
+func TestWheels(t *testing.T) {
+	all := []string{"I", "II", "III", "IV", "V", "VI", "VII", "VIII"}
+	for _, c := range []struct {
+		turnovers string
+		expected  []string
+	}{
+		{"Q", []string{"I"}},
+		{"EJ", []string{"II", "IV"}},
+		{"MZ", []string{"V", "VI", "VII", "VIII"}},
+		{"M", nil},
+		{"ABCDEFGHIJKLMNOPQRSTUVWXYZ", all},
+		{"", nil},
+	} {
+		wheels, err := Wheels([]rune(c.turnovers), all)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(wheels, c.expected) {
+			t.Errorf("Expected %v for %s, got %v", c.expected, c.turnovers, wheels)
+		}
+	}
+	if _, err := Wheels(nil, []string{"IX"}); err == nil {
+		t.Errorf("Expected an unknown rotor to fail")
+	}
+}
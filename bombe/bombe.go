@@ -0,0 +1,359 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+/*
+	Package bombe simulates the Turing-Welchman Bombe, which found Enigma
+	keys from a crib by testing every wheel order and rotor position at
+	once for each letter the crib's menu joins.
+*/
+package bombe
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+type Options struct {
+	// The rotors to choose wheel orders from, by name. Defaults to I, II
+	// and III.
+	Rotors []string
+
+	// The reflectors to try, by name. Defaults to B.
+	Reflectors []string
+
+	// The number of wheel orders to test at once. Defaults to GOMAXPROCS.
+	Workers int
+}
+
+func (o *Options) setDefaults() {
+	if len(o.Rotors) == 0 {
+		o.Rotors = []string{"I", "II", "III"}
+	}
+	if len(o.Reflectors) == 0 {
+		o.Reflectors = []string{"B"}
+	}
+	if o.Workers < 1 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+}
+
+/*
+	A Stop is a rotor position at which the menu is consistent with the test
+	letter being steckered to Partner. Like the real Bombe it assumes the
+	rings are at A and the middle rotor doesn't turn over during the crib,
+	so Positions are the windows at the start of the message with the rings
+	at AAA. Steckers holds every plug pair the stop implies for the letters
+	of the menu.
+*/
+type Stop struct {
+	Reflector       string
+	Rotors          []string
+	Positions       string
+	Letter, Partner rune
+	Steckers        string
+}
+
+/*
+	String writes the stop the way they were written up, the wheel order,
+	the drum positions and the stecker of the test letter, e.g.
+	"B II-V-III ZLM E/K".
+*/
+func (s Stop) String() string {
+	return fmt.Sprintf("%s %s %s %c/%c", s.Reflector, strings.Join(s.Rotors, "-"), s.Positions, s.Letter, s.Partner)
+}
+
+// Key returns the key of the stop with the plug pairs it implies.
+func (s Stop) Key() enigma.Key {
+	return enigma.Key{Reflector: s.Reflector, Rotors: s.Rotors, Positions: s.Positions, Plugboard: s.Steckers}
+}
+
+/*
+	Run tests every wheel order of the chosen rotors with each reflector at
+	every rotor position and returns the stops, in the order of the wheel
+	orders and then the positions.
+
+	At each position the test register, wired to the menu's test letter,
+	is given a voltage on one of its 26 wires, which stands for a guess at
+	the letter's stecker partner. Through the scramblers the voltage reaches
+	every wire implied by that guess, and through the diagonal board (A
+	steckered to B means B is steckered to A) the wires those imply. If the
+	guess is wrong, as it nearly always is, every wire of the test register
+	ends up live. The position is a stop when some single wire stays on its
+	own, and that wire is the partner.
+
+	Only the part of the menu joined to the test letter is used. If ctx is
+	cancelled the stops found so far are returned along with ctx.Err().
+*/
+func Run(ctx context.Context, menu *Menu, opts Options) ([]Stop, error) {
+	opts.setDefaults()
+	if len(menu.Edges) == 0 {
+		return nil, fmt.Errorf("the menu is empty")
+	}
+	rotors, err := wheels(opts.Rotors, enigma.RotorByName)
+	if err != nil {
+		return nil, err
+	}
+	reflectors, err := wheels(opts.Reflectors, enigma.ReflectorByName)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders [][3]int
+	for a := range rotors {
+		for b := range rotors {
+			for c := range rotors {
+				if a != b && a != c && b != c {
+					orders = append(orders, [3]int{a, b, c})
+				}
+			}
+		}
+	}
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("need at least 3 rotors, got %d", len(opts.Rotors))
+	}
+
+	test := menu.TestLetter()
+	m := newMachine(menu.Component(test), test)
+
+	// Each unit is a wheel order and reflector, the stops are kept by unit.
+	stops := make([][]Stop, len(orders)*len(reflectors))
+	units := make(chan int)
+	go func() {
+		defer close(units)
+		for i := range stops {
+			select {
+			case units <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m := *m // Each worker needs its own stack.
+			for u := range units {
+				o, r := orders[u/len(reflectors)], u%len(reflectors)
+				found := m.run(ctx, rotors[o[0]], rotors[o[1]], rotors[o[2]], reflectors[r])
+				for j := range found {
+					found[j].Reflector = opts.Reflectors[r]
+					found[j].Rotors = []string{opts.Rotors[o[0]], opts.Rotors[o[1]], opts.Rotors[o[2]]}
+				}
+				stops[u] = found
+			}
+		}()
+	}
+	wg.Wait()
+
+	var all []Stop
+	for _, s := range stops {
+		all = append(all, s...)
+	}
+	return all, ctx.Err()
+}
+
+func wheels(names []string, byName func(string) (*enigma.Rotor, error)) ([]*enigma.Rotor, error) {
+	var rotors []*enigma.Rotor
+	for _, name := range names {
+		r, err := byName(name)
+		if err != nil {
+			return nil, err
+		}
+		rotors = append(rotors, r)
+	}
+	return rotors, nil
+}
+
+// How a wheel connects its contacts at each of its 26 offsets.
+type wiring struct {
+	forward, reverse [26][26]byte
+}
+
+func newWiring(r *enigma.Rotor) *wiring {
+	w := &wiring{}
+	for o := 0; o < 26; o++ {
+		for x := 0; x < 26; x++ {
+			y := int(r.Get(rune('A'+(x+o)%26), false)-'A') - o
+			y = (y + 26) % 26
+			w.forward[o][x] = byte(y)
+			w.reverse[o][y] = byte(x)
+		}
+	}
+	return w
+}
+
+// A scrambler's connections, in both directions as it is symmetric.
+type scrambler [26]byte
+
+// A connection from a letter of the menu through the scrambler at an edge.
+type link struct {
+	to     int
+	offset int // From the start position of the right rotor.
+}
+
+// A Bombe wired up from a menu.
+type machine struct {
+	test    int
+	letters []int
+	links   [26][]link
+	stack   [][2]int // Reused by energize.
+}
+
+func newMachine(menu *Menu, test rune) *machine {
+	m := &machine{test: int(test - 'A')}
+	for _, c := range menu.Letters() {
+		m.letters = append(m.letters, int(c-'A'))
+	}
+	for _, e := range menu.Edges {
+		a, b := int(e.A-'A'), int(e.B-'A')
+		// The rotors step before each letter.
+		offset := (e.Position + 1) % 26
+		m.links[a] = append(m.links[a], link{b, offset})
+		m.links[b] = append(m.links[b], link{a, offset})
+	}
+	return m
+}
+
+// The wires that are live, by register and wire.
+type registers [26][26]bool
+
+// Tests every position of one wheel order and reflector.
+func (m *machine) run(ctx context.Context, left, middle, right, reflector *enigma.Rotor) []Stop {
+	l, mid, r := newWiring(left), newWiring(middle), newWiring(right)
+	u := newWiring(reflector).forward[0]
+
+	var stops []Stop
+	var scramblers [26]scrambler
+	var live registers
+	for p1 := 0; p1 < 26; p1++ {
+		if ctx.Err() != nil {
+			return stops
+		}
+		for p2 := 0; p2 < 26; p2++ {
+			// Everything but the right rotor stays put.
+			var inner scrambler
+			for x := range inner {
+				y := l.forward[p1][mid.forward[p2][x]]
+				inner[x] = mid.reverse[p2][l.reverse[p1][u[y]]]
+			}
+			for p3 := range scramblers {
+				for x := range scramblers[p3] {
+					scramblers[p3][x] = r.reverse[p3][inner[r.forward[p3][x]]]
+				}
+			}
+
+			for p3 := 0; p3 < 26; p3++ {
+				live = registers{}
+				for x := 0; x < 26; x++ {
+					if live[m.test][x] {
+						continue
+					}
+					if m.energize(&live, &scramblers, p3, m.test, x) != 1 {
+						continue
+					}
+					// A lone wire, check what it implies with a fresh start.
+					var alone registers
+					m.energize(&alone, &scramblers, p3, m.test, x)
+					if steckers, ok := m.steckers(&alone); ok {
+						stops = append(stops, Stop{
+							Positions: string([]rune{rune('A' + p1), rune('A' + p2), rune('A' + p3)}),
+							Letter:    rune('A' + m.test),
+							Partner:   rune('A' + x),
+							Steckers:  steckers,
+						})
+					}
+				}
+			}
+		}
+	}
+	return stops
+}
+
+/*
+	Puts a voltage on a wire of a register and returns how many wires of the
+	test register it newly reaches.
+*/
+func (m *machine) energize(live *registers, scramblers *[26]scrambler, start, register, wire int) int {
+	reached := 0
+	stack := m.stack[:0]
+	push := func(c, x int) {
+		if !live[c][x] {
+			live[c][x] = true
+			if c == m.test {
+				reached++
+			}
+			stack = append(stack, [2]int{c, x})
+		}
+	}
+	push(register, wire)
+	for len(stack) > 0 {
+		c, x := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+		// The diagonal board.
+		push(x, c)
+		for _, l := range m.links[c] {
+			push(l.to, int(scramblers[(start+l.offset)%26][x]))
+		}
+	}
+	m.stack = stack
+	return reached
+}
+
+/*
+	Reads the plug pairs implied for the letters of the menu that have a
+	single live wire. They must not contradict each other, which is the
+	check the operators made on each stop before trying it on an Enigma.
+*/
+func (m *machine) steckers(live *registers) (string, bool) {
+	var partner [26]int
+	for i := range partner {
+		partner[i] = -1
+	}
+	for _, c := range m.letters {
+		found := -1
+		for x, ok := range live[c] {
+			if ok && found >= 0 {
+				found = -2
+				break
+			} else if ok {
+				found = x
+			}
+		}
+		if found < 0 {
+			continue
+		}
+		if (partner[c] >= 0 && partner[c] != found) ||
+			(partner[found] >= 0 && partner[found] != c) {
+			return "", false
+		}
+		partner[c], partner[found] = found, c
+	}
+	var pairs []string
+	for c, x := range partner {
+		if x > c {
+			pairs = append(pairs, string([]rune{rune('A' + c), rune('A' + x)}))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " "), true
+}
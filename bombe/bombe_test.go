@@ -0,0 +1,152 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package bombe
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping full test in short mode.")
+	}
+
+	// The right rotor doesn't reach its notch during the message.
+	key := enigma.Key{Reflector: "B", Rotors: []string{"II", "I", "III"}, Positions: "QMW",
+		Plugboard: "AZ EK HN QT RW"}
+	m, err := key.NewMachine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	crib := "WETTERVORHERSAGEBISKAYA"
+	ciphertext := m.Encrypt("XX" + crib)
+	menu, err := NewMenu(ciphertext, crib, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(menu.Loops()) < 3 {
+		t.Fatalf("Expected a menu with loops, got %s", menu)
+	}
+
+	stops, err := Run(context.Background(), menu, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found *Stop
+	for i, s := range stops {
+		if s.Positions == key.Positions && strings.Join(s.Rotors, "-") == "II-I-III" {
+			found = &stops[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("Expected a stop at B II-I-III QMW, got %v", stops)
+	}
+	if len(stops) > 50 {
+		t.Errorf("Expected only a few stops, got %d", len(stops))
+	}
+
+	// Every pair implied must be right, and they must decrypt the crib.
+	for _, pair := range strings.Fields(found.Steckers) {
+		if !strings.Contains(key.Plugboard, pair) {
+			t.Errorf("Expected %s to be in %s", pair, key.Plugboard)
+		}
+	}
+	k := found.Key()
+	k.Plugboard = key.Plugboard
+	m, _ = k.NewMachine()
+	if p := m.Encrypt(ciphertext); p != "XX"+crib {
+		t.Errorf("Expected XX%s, got %s", crib, p)
+	}
+	if s := found.String(); !strings.HasPrefix(s, "B II-I-III QMW "+string(found.Letter)+"/") {
+		t.Errorf("Expected the stop to be written B II-I-III QMW, got %s", s)
+	}
+}
+
+/*
+	The first part of a German army message of 22 June 1941, the start of
+	Operation Barbarossa, with its key from the day's key sheet as published
+	by Geoff Sullivan and Frode Weierud. The message begins with the routine
+	AUFKLXABTEILUNGXVON, reconnaissance unit from, which makes a crib. With
+	the rings at BUL and the message setting BLA the Bombe, which assumes
+	rings at AAA, should stop at ARP. With the real rings the right rotor
+	doesn't reach its notch during the crib.
+*/
+func TestRunBarbarossa(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping full test in short mode.")
+	}
+
+	key := enigma.Key{Reflector: "B", Rotors: []string{"II", "IV", "V"}, Rings: "BUL", Positions: "BLA",
+		Plugboard: "AV BS CG DL FU HZ IN KM OW RX"}
+	ciphertext := "EDPUDNRGYSZRCXNUYTPOMRMBOFKTBZREZKMLXLVEFGUEYSIOZVEQMIKUBPMMYLKLTTDEISMDICAGYKUACTCDOMOHWXMUUIAUBSTSLRNBZSZWNRFXWFYSSXJZVIJHIDISHPRKLKAYUPADTXQSPINQMATLPIFSVKDASCTACDPBOPVHJK"
+	crib := "AUFKLXABTEILUNGXVONXKURTI"
+	menu, err := NewMenu(ciphertext[:len(crib)], crib, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stops, err := Run(context.Background(), menu, Options{Rotors: []string{"I", "II", "III", "IV", "V"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The menu has enough loops that the right stop is the only one.
+	if len(stops) != 1 {
+		t.Fatalf("Expected one stop, got %v", stops)
+	}
+	if s := stops[0].String(); s != "B II-IV-V ARP U/F" {
+		t.Errorf("Expected B II-IV-V ARP U/F, got %s", s)
+	}
+	if stops[0].Steckers != key.Plugboard {
+		t.Errorf("Expected %s, got %s", key.Plugboard, stops[0].Steckers)
+	}
+
+	/*
+		At AAA the right rotor would turn the middle one over part way
+		through the crib, so as the codebreakers did, find the rings. Moving
+		the stop's positions on by the published rings gives the published
+		message setting, and that key reads the whole message.
+	*/
+	setting := []byte(stops[0].Positions)
+	for i := range setting {
+		setting[i] = 'A' + (setting[i]-'A'+key.Rings[i]-'A')%26
+	}
+	if string(setting) != key.Positions {
+		t.Errorf("Expected %s, got %s", key.Positions, setting)
+	}
+	m, err := key.NewMachine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := m.Encrypt(ciphertext); !strings.HasPrefix(p, crib+"NOWAXKURTINOWAXNORDWESTL") {
+		t.Errorf("Expected the message to begin %s, got %s", crib, p)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	menu, _ := NewMenu("QWERTY", "ABCDEF", 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, menu, Options{}); err != context.Canceled {
+		t.Errorf("Expected %s, got %v", context.Canceled, err)
+	}
+	if _, err := Run(context.Background(), menu, Options{Rotors: []string{"I", "II"}}); err == nil {
+		t.Errorf("Expected too few rotors to fail")
+	}
+}
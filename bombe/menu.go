@@ -0,0 +1,224 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package bombe
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+	An Edge joins a letter of the crib to the letter of the ciphertext it
+	lies under. Position is the index of the ciphertext letter in the
+	message, from 0.
+*/
+type Edge struct {
+	Position int
+	A, B     rune
+}
+
+func (e Edge) String() string {
+	return fmt.Sprintf("%c%d%c", e.A, e.Position, e.B)
+}
+
+// The letter at the other end of the edge.
+func (e Edge) other(c rune) rune {
+	if c == e.A {
+		return e.B
+	}
+	return e.A
+}
+
+/*
+	A Menu is the graph a Bombe is wired up from: the letters of a crib and
+	the ciphertext under it are the nodes, and each position of the crib an
+	edge between its two letters. Loops in the menu are what let a Bombe
+	reject wrong settings.
+*/
+type Menu struct {
+	Edges []Edge `json:"edges"`
+}
+
+/*
+	NewMenu builds the menu of a crib placed at the offset into the
+	ciphertext. A crib letter above the same letter of the ciphertext is an
+	error, as an Enigma never encrypts a letter to itself.
+*/
+func NewMenu(ciphertext, crib string, offset int) (*Menu, error) {
+	ciphertext, crib = strings.ToUpper(ciphertext), strings.ToUpper(crib)
+	if strings.IndexFunc(ciphertext, notLetter) >= 0 || strings.IndexFunc(crib, notLetter) >= 0 {
+		return nil, errors.New("the message and crib must only contain the letters A-Z")
+	}
+	if crib == "" || offset < 0 || offset+len(crib) > len(ciphertext) {
+		return nil, fmt.Errorf("a crib of %d letters doesn't fit at %d in a message of %d",
+			len(crib), offset, len(ciphertext))
+	}
+	m := &Menu{}
+	for i := 0; i < len(crib); i++ {
+		a, b := rune(crib[i]), rune(ciphertext[offset+i])
+		if a == b {
+			return nil, fmt.Errorf("%c can't encrypt to itself at %d", a, offset+i)
+		}
+		m.Edges = append(m.Edges, Edge{offset + i, a, b})
+	}
+	return m, nil
+}
+
+// Letters returns the letters of the menu in alphabetical order.
+func (m *Menu) Letters() []rune {
+	var seen [26]bool
+	for _, e := range m.Edges {
+		seen[e.A-'A'], seen[e.B-'A'] = true, true
+	}
+	var letters []rune
+	for i, ok := range seen {
+		if ok {
+			letters = append(letters, rune('A'+i))
+		}
+	}
+	return letters
+}
+
+// The edges touching each letter, indexed from A.
+func (m *Menu) adjacent() [26][]Edge {
+	var adj [26][]Edge
+	for _, e := range m.Edges {
+		adj[e.A-'A'] = append(adj[e.A-'A'], e)
+		adj[e.B-'A'] = append(adj[e.B-'A'], e)
+	}
+	return adj
+}
+
+/*
+	TestLetter returns the letter with the most edges, ties going to the
+	first in the alphabet. A Bombe's test register is wired to it.
+*/
+func (m *Menu) TestLetter() rune {
+	adj := m.adjacent()
+	best := 0
+	for i := range adj {
+		if len(adj[i]) > len(adj[best]) {
+			best = i
+		}
+	}
+	return rune('A' + best)
+}
+
+/*
+	Component returns the part of the menu connected to the letter. Only
+	that part takes part in a test.
+*/
+func (m *Menu) Component(c rune) *Menu {
+	adj := m.adjacent()
+	var seen [26]bool
+	seen[c-'A'] = true
+	queue := []rune{c}
+	for len(queue) > 0 {
+		c, queue = queue[0], queue[1:]
+		for _, e := range adj[c-'A'] {
+			if o := e.other(c); !seen[o-'A'] {
+				seen[o-'A'] = true
+				queue = append(queue, o)
+			}
+		}
+	}
+	part := &Menu{}
+	for _, e := range m.Edges {
+		if seen[e.A-'A'] {
+			part.Edges = append(part.Edges, e)
+		}
+	}
+	return part
+}
+
+/*
+	Loops returns a set of independent loops of the menu, each one as the
+	edges around it. Every edge not in a spanning tree of the menu closes
+	one loop, so there are always edges - letters + parts of them.
+*/
+func (m *Menu) Loops() [][]Edge {
+	// Walks a spanning tree breadth first, remembering how each letter was
+	// reached.
+	var parent [26]*Edge
+	var depth [26]int
+	var seen [26]bool
+	inTree := make(map[int]bool)
+	adj := m.adjacent()
+	for _, start := range m.Letters() {
+		if seen[start-'A'] {
+			continue
+		}
+		seen[start-'A'] = true
+		queue := []rune{start}
+		for len(queue) > 0 {
+			c := queue[0]
+			queue = queue[1:]
+			for i := range adj[c-'A'] {
+				e := &adj[c-'A'][i]
+				if o := e.other(c); !seen[o-'A'] {
+					seen[o-'A'] = true
+					parent[o-'A'], depth[o-'A'] = e, depth[c-'A']+1
+					inTree[e.Position] = true
+					queue = append(queue, o)
+				}
+			}
+		}
+	}
+
+	var loops [][]Edge
+	for _, e := range m.Edges {
+		if inTree[e.Position] {
+			continue
+		}
+		// Climbs from both ends to where the paths meet.
+		a, b := e.A, e.B
+		var up, down []Edge
+		for a != b {
+			if depth[a-'A'] >= depth[b-'A'] {
+				up = append(up, *parent[a-'A'])
+				a = parent[a-'A'].other(a)
+			} else {
+				down = append(down, *parent[b-'A'])
+				b = parent[b-'A'].other(b)
+			}
+		}
+		loop := append([]Edge{e}, down...)
+		for i := len(up) - 1; i >= 0; i-- {
+			loop = append(loop, up[i])
+		}
+		loops = append(loops, loop)
+	}
+	sort.SliceStable(loops, func(i, j int) bool { return len(loops[i]) < len(loops[j]) })
+	return loops
+}
+
+/*
+	String lists the edges, each one written as its two letters with the
+	position between them, e.g. "E12K".
+*/
+func (m *Menu) String() string {
+	parts := make([]string, len(m.Edges))
+	for i, e := range m.Edges {
+		parts[i] = e.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+func notLetter(r rune) bool {
+	return r < 'A' || r > 'Z'
+}
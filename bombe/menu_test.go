@@ -0,0 +1,82 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package bombe
+
+import (
+	"testing"
+)
+
+func TestNewMenu(t *testing.T) {
+	m, err := NewMenu("XQCAB", "cba", 2)
+	if err == nil {
+		t.Errorf("Expected C over C to fail, got %s", m)
+	}
+	m, err = NewMenu("XQCAB", "bcd", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := m.String(); s != "B2C C3A D4B" {
+		t.Errorf("Expected B2C C3A D4B, got %s", s)
+	}
+	if l := string(m.Letters()); l != "ABCD" {
+		t.Errorf("Expected ABCD, got %s", l)
+	}
+
+	for _, args := range [][2]string{{"ABC", ""}, {"ABC", "BCDE"}, {"AB C", "B"}} {
+		if _, err := NewMenu(args[0], args[1], 0); err == nil {
+			t.Errorf("Expected NewMenu(%q, %q) to fail", args[0], args[1])
+		}
+	}
+	if _, err := NewMenu("ABC", "BC", 2); err == nil {
+		t.Errorf("Expected a crib running off the end to fail")
+	}
+}
+
+func TestLoops(t *testing.T) {
+	m := &Menu{Edges: []Edge{{0, 'A', 'B'}, {1, 'B', 'C'}, {2, 'C', 'A'}, {3, 'C', 'D'},
+		{4, 'X', 'Y'}, {5, 'Y', 'X'}, {6, 'D', 'E'}, {7, 'E', 'B'}}}
+	loops := m.Loops()
+	// 8 edges, 7 letters and 2 parts.
+	if len(loops) != 3 {
+		t.Fatalf("Expected 3 loops, got %v", loops)
+	}
+	if len(loops[0]) != 2 || loops[0][0].Position != 5 {
+		t.Errorf("Expected the loop of X and Y first, got %v", loops[0])
+	}
+	for _, loop := range loops {
+		// Each loop must join up, every letter on it touching two edges.
+		count := make(map[rune]int)
+		for _, e := range loop {
+			count[e.A]++
+			count[e.B]++
+		}
+		for c, n := range count {
+			if n != 2 {
+				t.Errorf("Expected %c to be on 2 edges of %v, got %d", c, loop, n)
+			}
+		}
+	}
+
+	if c := m.TestLetter(); c != 'B' {
+		t.Errorf("Expected B, got %c", c)
+	}
+	if s := m.Component('Y').String(); s != "X4Y Y5X" {
+		t.Errorf("Expected X4Y Y5X, got %s", s)
+	}
+	if n := len(m.Component('A').Loops()); n != 2 {
+		t.Errorf("Expected 2 loops, got %d", n)
+	}
+}
@@ -0,0 +1,200 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package bombe
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// The rotor positions a Bombe tests for each wheel order.
+const positions = 26 * 26 * 26
+
+/*
+	Quality describes how well a menu would do on a Bombe. It only counts
+	the part of the menu joined to its test letter, the part that is used.
+*/
+type Quality struct {
+	TestLetter string `json:"test"`
+	Letters    int    `json:"letters"`
+	Edges      int    `json:"edges"`
+	Loops      int    `json:"loops"`
+
+	// The positions of the message the menu covers, first to last.
+	Span int `json:"span"`
+
+	/*
+		The chance that the middle rotor turns over somewhere in the span,
+		which a Bombe can't follow, so it misses the right stop. For a
+		rotor with one notch this is (Span-1)/26.
+	*/
+	Turnover float64 `json:"turnover"`
+
+	/*
+		The stops to expect at wrong positions for each wheel order. By
+		Turing's rule of thumb each loop cuts them by a factor of 26, so a
+		menu without loops stops everywhere.
+	*/
+	FalseStops float64 `json:"false_stops"`
+}
+
+// Quality rates the menu.
+func (m *Menu) Quality() Quality {
+	test := m.TestLetter()
+	part := m.Component(test)
+	q := Quality{
+		TestLetter: string(test),
+		Letters:    len(part.Letters()),
+		Edges:      len(part.Edges),
+	}
+	if q.Edges == 0 {
+		return q
+	}
+	q.Loops = q.Edges - q.Letters + 1
+	first, last := part.Edges[0].Position, part.Edges[0].Position
+	for _, e := range part.Edges {
+		if e.Position < first {
+			first = e.Position
+		}
+		if e.Position > last {
+			last = e.Position
+		}
+	}
+	q.Span = last - first + 1
+	q.Turnover = math.Min(1, float64(q.Span-1)/26)
+	q.FalseStops = positions / math.Pow(26, float64(q.Loops))
+	return q
+}
+
+/*
+	Better reports whether a menu of quality q is a better choice than one
+	of quality o. Once both expect less than one false stop per wheel order
+	the one less likely to meet a turnover is better, otherwise the one with
+	fewer false stops.
+*/
+func (q Quality) Better(o Quality) bool {
+	if q.FalseStops < 1 && o.FalseStops < 1 && q.Turnover != o.Turnover {
+		return q.Turnover < o.Turnover
+	}
+	if q.FalseStops != o.FalseStops {
+		return q.FalseStops < o.FalseStops
+	}
+	return q.Turnover < o.Turnover
+}
+
+// A candidate menu and how well it would do.
+type Choice struct {
+	Menu    *Menu   `json:"menu"`
+	Quality Quality `json:"quality"`
+}
+
+/*
+	Menus enumerates the menus a crib placed at the offset can give: the
+	part joined to the test letter of every run of consecutive crib letters
+	covering at most span positions of the message, 0 for no limit. The
+	choices come back best first.
+*/
+func Menus(ciphertext, crib string, offset, span int) ([]Choice, error) {
+	full, err := NewMenu(ciphertext, crib, offset)
+	if err != nil {
+		return nil, err
+	}
+	if span <= 0 || span > len(full.Edges) {
+		span = len(full.Edges)
+	}
+
+	var choices []Choice
+	seen := make(map[string]bool)
+	for first := range full.Edges {
+		for last := first + 1; last <= len(full.Edges) && last-first <= span; last++ {
+			run := &Menu{Edges: full.Edges[first:last]}
+			part := run.Component(run.TestLetter())
+			if seen[part.String()] {
+				continue
+			}
+			seen[part.String()] = true
+			choices = append(choices, Choice{part, part.Quality()})
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool {
+		return choices[i].Quality.Better(choices[j].Quality)
+	})
+	return choices, nil
+}
+
+/*
+	WriteDOT draws the menu as a Graphviz graph, the letters joined by the
+	positions of the crib. The test letter is drawn double and the edges
+	that are on loops in bold.
+*/
+func (m *Menu) WriteDOT(w io.Writer) error {
+	onLoop := make(map[int]bool)
+	for _, loop := range m.Loops() {
+		for _, e := range loop {
+			onLoop[e.Position] = true
+		}
+	}
+	d := &dotWriter{w: w}
+	d.printf("graph menu {\n")
+	d.printf("\tnode [shape=circle];\n")
+	d.printf("\t%c [shape=doublecircle];\n", m.TestLetter())
+	for _, e := range m.Edges {
+		style := ""
+		if onLoop[e.Position] {
+			style = ", style=bold"
+		}
+		d.printf("\t%c -- %c [label=\"%d\"%s];\n", e.A, e.B, e.Position, style)
+	}
+	d.printf("}\n")
+	return d.err
+}
+
+// Keeps the first error so the drawing code needn't check every write.
+type dotWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (d *dotWriter) printf(format string, args ...interface{}) {
+	if d.err == nil {
+		_, d.err = fmt.Fprintf(d.w, format, args...)
+	}
+}
+
+// Menus are written as JSON with each edge as a string, e.g. "E12K".
+func (e Edge) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.String())
+}
+
+func (e *Edge) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if len(s) < 3 || notLetter(rune(s[0])) || notLetter(rune(s[len(s)-1])) {
+		return fmt.Errorf("invalid menu edge %q", s)
+	}
+	p, err := strconv.Atoi(s[1 : len(s)-1])
+	if err != nil || p < 0 {
+		return fmt.Errorf("invalid menu edge %q", s)
+	}
+	*e = Edge{p, rune(s[0]), rune(s[len(s)-1])}
+	return nil
+}
@@ -0,0 +1,117 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package bombe
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestQuality(t *testing.T) {
+	m := &Menu{Edges: []Edge{{0, 'A', 'B'}, {1, 'B', 'C'}, {2, 'C', 'A'}, {3, 'C', 'D'},
+		{4, 'X', 'Y'}, {5, 'Y', 'X'}, {6, 'D', 'E'}, {7, 'E', 'B'}}}
+	q := m.Quality()
+	expected := Quality{TestLetter: "B", Letters: 5, Edges: 6, Loops: 2, Span: 8,
+		Turnover: 7.0 / 26, FalseStops: 26}
+	if q != expected {
+		t.Errorf("Expected %+v, got %+v", expected, q)
+	}
+
+	if !q.Better(Quality{FalseStops: 676}) || q.Better(Quality{FalseStops: 1}) {
+		t.Errorf("Expected fewer false stops to be better")
+	}
+	few := Quality{FalseStops: 0.5, Turnover: 0.5}
+	if !few.Better(Quality{FalseStops: 0.01, Turnover: 0.6}) {
+		t.Errorf("Expected less chance of a turnover to be better once there are few false stops")
+	}
+}
+
+func TestMenus(t *testing.T) {
+	choices, err := Menus("INATBIDWFUSQOITPHQZUGWETK", "WETTERVORHERSAGEBISKAYA", 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	best := choices[0].Quality
+	if best.FalseStops >= 1 {
+		t.Errorf("Expected the best menu to have few false stops, got %+v", best)
+	}
+	for i, c := range choices {
+		if c.Quality != c.Menu.Quality() {
+			t.Errorf("Expected %+v, got %+v", c.Menu.Quality(), c.Quality)
+		}
+		if i > 0 && c.Quality.Better(choices[i-1].Quality) {
+			t.Errorf("Expected %s to come before %s", c.Menu, choices[i-1].Menu)
+		}
+	}
+
+	choices, err = Menus("INATBIDWFUSQOITPHQZUGWETK", "WETTERVORHERSAGEBISKAYA", 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range choices {
+		if c.Quality.Span > 10 {
+			t.Errorf("Expected a span of at most 10, got %s", c.Menu)
+		}
+	}
+	if _, err := Menus("ABC", "A", 0, 0); err == nil {
+		t.Errorf("Expected A over A to fail")
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	m := &Menu{Edges: []Edge{{3, 'A', 'B'}, {4, 'B', 'C'}, {5, 'C', 'A'}, {6, 'C', 'D'}}}
+	var buf bytes.Buffer
+	if err := m.WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `graph menu {
+	node [shape=circle];
+	C [shape=doublecircle];
+	A -- B [label="3", style=bold];
+	B -- C [label="4", style=bold];
+	C -- A [label="5", style=bold];
+	C -- D [label="6"];
+}
+`
+	if buf.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, buf.String())
+	}
+}
+
+func TestMenuJSON(t *testing.T) {
+	m := &Menu{Edges: []Edge{{3, 'A', 'B'}, {12, 'E', 'K'}}}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"edges":["A3B","E12K"]}` {
+		t.Errorf(`Expected {"edges":["A3B","E12K"]}, got %s`, b)
+	}
+	var read Menu
+	if err := json.Unmarshal(b, &read); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&read, m) {
+		t.Errorf("Expected %s, got %s", m, &read)
+	}
+	for _, invalid := range []string{`{"edges":["AB"]}`, `{"edges":["A-1B"]}`, `{"edges":["3AB"]}`, `{"edges":[3]}`} {
+		if err := json.Unmarshal([]byte(invalid), &read); err == nil {
+			t.Errorf("Expected %s to fail", invalid)
+		}
+	}
+}
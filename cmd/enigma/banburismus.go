@@ -0,0 +1,76 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mww/enigma-go/banburismus"
+	"github.com/mww/enigma-go/frequency"
+)
+
+// Slides messages over each other to find the right rotor.
+func slideMessages(args []string, in io.Reader, out io.Writer) error {
+	flags := newFlagSet("banburismus")
+	inFile := flags.String("in", "-", "Read the messages, a setting and the text a line, from this file, - for stdin.")
+	language := flags.String("language", "german-x", "The language of the plaintexts, by name or a frequency file.")
+	threshold := flags.Float64("threshold", 10, "The decibans for a pair of messages to count as in depth.")
+	wheels := flags.String("wheels", "naval",
+		"The rotors the right one is among, or army for I-V or naval for I-VIII.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if set, ok := wheelSets[strings.ToLower(*wheels)]; ok {
+		*wheels = set
+	}
+
+	text, err := readInput(*inFile, in)
+	if err != nil {
+		return err
+	}
+	var messages []banburismus.Message
+	for _, line := range strings.Split(strings.ToUpper(text), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		messages = append(messages, banburismus.Message{Setting: fields[0], Text: strings.Join(fields[1:], "")})
+	}
+	l, err := frequency.LookupLanguage(*language)
+	if err != nil {
+		return err
+	}
+
+	r, err := banburismus.Analyze(messages, banburismus.Options{
+		Language:  l,
+		Threshold: threshold,
+		Rotors:    strings.Split(*wheels, ","),
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range r.Pairs {
+		if p.Score >= *threshold {
+			fmt.Fprintf(out, "%s %s at %+d: %d repeats in %d, %.1f db\n", messages[p.A].Setting,
+				messages[p.B].Setting, p.Offset, p.Repeats, p.Overlap, p.Score)
+		}
+	}
+	_, err = fmt.Fprintf(out, "Chains:    %s\nTurnovers: %s\nWheels:    %s\n",
+		strings.Join(r.Chains, " "), string(r.Turnovers), strings.Join(r.Wheels, " "))
+	return err
+}
@@ -0,0 +1,72 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mww/enigma-go/bombe"
+)
+
+// Runs a Bombe on the menu of a crib.
+func runBombe(args []string, in io.Reader, out io.Writer) error {
+	flags := newFlagSet("bombe")
+	message := messageFlags(flags, in, "The encrypted message.")
+	word := flags.String("crib", "", "The probable plaintext.")
+	offset := flags.Int("offset", 0, "Where the crib lies in the message, from 0.")
+	wheels := flags.String("wheels", "I,II,III",
+		"The rotors to choose the wheel order from, or army for I-V or naval for I-VIII.")
+	reflectors := flags.String("reflectors", "B", "The reflectors to try.")
+	workers := flags.Int("workers", 0, "The number of wheel orders to test at once.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *word == "" {
+		return errors.New("--crib is required")
+	}
+	if set, ok := wheelSets[strings.ToLower(*wheels)]; ok {
+		*wheels = set
+	}
+
+	text, err := message()
+	if err != nil {
+		return err
+	}
+	menu, err := bombe.NewMenu(text, *word, *offset)
+	if err != nil {
+		return err
+	}
+	test := menu.TestLetter()
+	fmt.Fprintf(out, "Menu:  %s\nLoops: %d, test letter %c\n", menu, len(menu.Component(test).Loops()), test)
+
+	stops, err := bombe.Run(context.Background(), menu, bombe.Options{
+		Rotors:     strings.Split(*wheels, ","),
+		Reflectors: strings.Split(*reflectors, ","),
+		Workers:    *workers,
+	})
+	if err != nil {
+		return err
+	}
+	for _, s := range stops {
+		fmt.Fprintf(out, "%s  %s\n", s, s.Steckers)
+	}
+	_, err = fmt.Fprintf(out, "%d stops\n", len(stops))
+	return err
+}
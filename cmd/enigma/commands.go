@@ -0,0 +1,441 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"time"
+
+	enigma "github.com/mww/enigma-go"
+	"github.com/mww/enigma-go/crack"
+	"github.com/mww/enigma-go/frequency"
+	"github.com/mww/enigma-go/ngram"
+	"github.com/mww/enigma-go/plaintext"
+)
+
+type command struct {
+	name, args, description string
+	run                     func(args []string, in io.Reader, out io.Writer) error
+}
+
+var commands []*command
+
+func init() {
+	// Assigned here because usage refers back to commands.
+	commands = []*command{
+		{"encrypt", "[KEY] [--convention=NAME] [--in=FILE] [--out=FILE]",
+			"Encrypt text with a known key.", encrypt},
+		{"decrypt", "[KEY] [--convention=NAME] [--in=FILE] [--out=FILE]",
+			"Decrypt a message with a known key.", decrypt},
+		{"crack", "--message=MESSAGE | --in=FILE [--results=N] [--workers=N]\n" +
+			"               [--wheels=I,II,III|army|naval] [--reflectors=A,B,C] [--rings] [--progress]\n" +
+			"               [--steckers=N [--candidates=N] [--strategy=hillclimb|restart|anneal]]\n" +
+			"               [--scorer=NAME [--language=NAME] | --model=FILE] [--checkpoint=FILE [--checkpoint-interval=1m]]",
+			"Search for the key of a message.", crackMessage},
+		{"split", "--shards=N --dir=DIR --message=MESSAGE | --in=FILE [--results=N]\n" +
+			"               [--wheels=I,II,III|army|naval] [--reflectors=A,B,C] [--rings]\n" +
+			"               [--steckers=N [--candidates=N] [--strategy=hillclimb|restart|anneal]]\n" +
+			"               [--scorer=NAME [--language=NAME] | --model=FILE]",
+			"Split a search into jobs for other processes.", split},
+		{"work", "--job=FILE [--out=FILE] [--workers=N] [--progress] [--checkpoint=FILE]",
+			"Run one job from split.", work},
+		{"merge", "[--results=N] FILE...",
+			"Combine the results of the jobs from split.", merge},
+		{"crib", "--crib=WORD --message=MESSAGE | --in=FILE",
+			"Show where probable plaintext can lie under a message.", cribPositions},
+		{"menu", "--crib=WORD [--offset=N] [--span=N] [--format=text|json|dot] --message=MESSAGE | --in=FILE",
+			"Choose the best Bombe menu from a crib.", chooseMenu},
+		{"bombe", "--crib=WORD [--offset=N] --message=MESSAGE | --in=FILE\n" +
+			"               [--wheels=I,II,III|army|naval] [--reflectors=B] [--workers=N]",
+			"Find the rotor positions a crib allows, as a Bombe did.", runBombe},
+		{"rejewski", "[--in=FILE] [--catalog=FILE] [--wheels=I,II,III|army|naval] [--reflectors=B]",
+			"Look up the ground setting of a day's doubled indicators.", lookupIndicators},
+		{"zygalski", "[--in=FILE] [--wheels=I,II,III|army|naval] [--reflectors=B] [--sheets=DIR]",
+			"Find the wheel order and rings of indicators sent with a ground setting.", stackSheets},
+		{"banburismus", "[--in=FILE] [--language=german-x] [--threshold=10] [--wheels=naval]",
+			"Find the right rotor from messages whose settings overlap.", slideMessages},
+		{"train", "[--n=4] [--format=binary|text] [--out=FILE] [FILE...]",
+			"Count the runs of letters in text, for crack --model.", train},
+		{"keygen", "[--seed=N] [--json]",
+			"Generate a random key.", keygen},
+		{"info", "[KEY]",
+			"Describe the machine a key sets up.", info},
+		{"interactive", "",
+			"Run a simulator with a lampboard in the terminal.", interactive},
+		{"serve", "[--addr=HOST:PORT] [--max-jobs=N] [--keep-jobs=1h]",
+			"Serve a JSON API for the web front end.", serve},
+	}
+}
+
+const keyUsage = `KEY is given with the flags --reflector=B --rotors=I,II,III --rings=AAA
+--positions=AAA --plugboard="AB CD", all of which have defaults.`
+
+/*
+	Runs the command named by the first argument. For compatibility anything
+	that doesn't name a command, like "--message=...", is passed to crack.
+*/
+func runCommand(args []string, in io.Reader, out io.Writer) error {
+	if len(args) == 0 {
+		return usage()
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], in, out)
+		}
+	}
+	if strings.HasPrefix(args[0], "-") {
+		return crackMessage(args, in, out)
+	}
+	return usage()
+}
+
+func usage() error {
+	var buf bytes.Buffer
+	buf.WriteString("usage: enigma COMMAND [FLAGS]\n\n")
+	for _, c := range commands {
+		fmt.Fprintf(&buf, "  %-12s %s\n  %-12s %s\n", c.name, c.description, "", c.args)
+	}
+	buf.WriteString("\n" + keyUsage)
+	return errors.New(buf.String())
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet(name, flag.ContinueOnError)
+}
+
+// Adds the flags describing a key, the returned function reads them.
+func keyFlags(flags *flag.FlagSet) func() enigma.Key {
+	reflector := flags.String("reflector", "B", "The reflector, A, B or C.")
+	rotors := flags.String("rotors", "I,II,III", "The rotors from left to right, I-VIII.")
+	rings := flags.String("rings", "AAA", "The ring settings.")
+	positions := flags.String("positions", "AAA", "The starting positions.")
+	plugboard := flags.String("plugboard", "", `The plug pairs, e.g. "AB CD".`)
+	return func() enigma.Key {
+		return enigma.Key{
+			Reflector: *reflector,
+			Rotors:    strings.Split(*rotors, ","),
+			Rings:     *rings,
+			Positions: *positions,
+			Plugboard: *plugboard,
+		}
+	}
+}
+
+// The --in and --out flags.
+type fileFlags struct {
+	in, out *string
+}
+
+func addFileFlags(flags *flag.FlagSet) *fileFlags {
+	return &fileFlags{
+		in:  flags.String("in", "", "Read from this file instead of stdin."),
+		out: flags.String("out", "", "Write to this file instead of stdout."),
+	}
+}
+
+func (f *fileFlags) read(in io.Reader) (string, error) {
+	return readInput(*f.in, in)
+}
+
+// Writes text and a newline to the --out file, or to out if there isn't one.
+func (f *fileFlags) write(out io.Writer, text string) error {
+	if *f.out == "" {
+		_, err := fmt.Fprintln(out, text)
+		return err
+	}
+	return ioutil.WriteFile(*f.out, []byte(text+"\n"), 0644)
+}
+
+/*
+	Adds --message and --in for the message a command works on, the returned
+	function reads it in capitals. Only one of them may be set.
+*/
+func messageFlags(flags *flag.FlagSet, in io.Reader, usage string) func() (string, error) {
+	message := flags.String("message", "", usage)
+	inFile := flags.String("in", "", "Read the message from this file, - for stdin.")
+	return func() (string, error) {
+		if *message != "" && *inFile != "" {
+			return "", errors.New("use --message or --in, not both")
+		}
+		if *inFile != "" {
+			text, err := readInput(*inFile, in)
+			return strings.ToUpper(text), err
+		}
+		return strings.ToUpper(*message), nil
+	}
+}
+
+func readInput(file string, in io.Reader) (string, error) {
+	var b []byte
+	var err error
+	if file == "" || file == "-" {
+		b, err = ioutil.ReadAll(in)
+	} else {
+		b, err = ioutil.ReadFile(file)
+	}
+	return strings.TrimSpace(string(b)), err
+}
+
+var conventions = map[string]func() *plaintext.Convention{
+	"german":  plaintext.German,
+	"naval":   plaintext.Naval,
+	"english": plaintext.English,
+}
+
+func convention(name string) (enigma.Codec, error) {
+	if name == "" {
+		return nil, nil
+	}
+	c, ok := conventions[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown convention %q, expected german, naval or english", name)
+	}
+	return c(), nil
+}
+
+func encrypt(args []string, in io.Reader, out io.Writer) error {
+	return transform("encrypt", args, in, out, false)
+}
+
+func decrypt(args []string, in io.Reader, out io.Writer) error {
+	return transform("decrypt", args, in, out, true)
+}
+
+/*
+	Encrypts or decrypts the input. The only difference is which direction
+	a plaintext convention is applied in.
+*/
+func transform(name string, args []string, in io.Reader, out io.Writer, decrypting bool) error {
+	flags := newFlagSet(name)
+	key := keyFlags(flags)
+	files := addFileFlags(flags)
+	conventionName := flags.String("convention", "",
+		"Convert between readable text and plaintext using german, naval or english conventions.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	k := key()
+	m, err := k.NewMachine()
+	if err != nil {
+		return err
+	}
+	codec, err := convention(*conventionName)
+	if err != nil {
+		return err
+	}
+	text, err := files.read(in)
+	if err != nil {
+		return err
+	}
+
+	var result string
+	switch {
+	case codec == nil:
+		result = m.Encrypt(text)
+	case decrypting:
+		result = m.DecryptText(text, codec)
+	default:
+		result = m.EncryptText(text, codec)
+	}
+	return files.write(out, result)
+}
+
+var wheelSets = map[string]string{
+	"army":  "I,II,III,IV,V",
+	"naval": "I,II,III,IV,V,VI,VII,VIII",
+}
+
+// Adds the flags describing what to search, the returned function reads them.
+func searchFlags(flags *flag.FlagSet, in io.Reader) func() (string, crack.Options, error) {
+	message := messageFlags(flags, in, "The encrypted message to crack.")
+	numResults := flags.Int("results", 3, "The number of results to display")
+	wheels := flags.String("wheels", "I,II,III",
+		"The rotors to choose the wheel order from, or army for I-V or naval for I-VIII.")
+	reflectors := flags.String("reflectors", "A,B,C", "The reflectors to try.")
+	rings := flags.Bool("rings", false, "Also try every ring setting of the middle and right rotors.")
+	steckers := flags.Int("steckers", 0, "The most plug pairs to look for by hill climbing.")
+	candidates := flags.Int("candidates", 100, "The number of rotor settings to look for plug pairs with.")
+	strategy := flags.String("strategy", "hillclimb", "How to look for plug pairs: hillclimb, restart or anneal.")
+	scorer := flags.String("scorer", "", "How to rate plaintexts: "+strings.Join(crack.ScorerNames(), ", ")+
+		". Defaults to unigram, or bigram with --steckers.")
+	model := flags.String("model", "", "Rate plaintexts with an n-gram model from train instead of --scorer.")
+	language := flags.String("language", "", "The language for --scorer: "+
+		strings.Join(frequency.LanguageNames(), ", ")+", or a file of letter frequencies.")
+	return func() (string, crack.Options, error) {
+		if set, ok := wheelSets[strings.ToLower(*wheels)]; ok {
+			*wheels = set
+		}
+		s, err := crack.StrategyByName(*strategy)
+		if err != nil {
+			return "", crack.Options{}, err
+		}
+		var sc crack.Scorer
+		if *model != "" {
+			if sc, err = ngram.Load(*model); err != nil {
+				return "", crack.Options{}, err
+			}
+		} else if *language != "" {
+			// Other languages only have the scorers that count letters.
+			if *scorer == "" {
+				*scorer = "unigram"
+			}
+			l, err := frequency.LookupLanguage(*language)
+			if err != nil {
+				return "", crack.Options{}, err
+			}
+			if sc, err = crack.ScorerForLanguage(*scorer, l); err != nil {
+				return "", crack.Options{}, err
+			}
+		} else if *scorer != "" {
+			if sc, err = crack.ScorerByName(*scorer); err != nil {
+				return "", crack.Options{}, err
+			}
+		}
+		opts := crack.Options{
+			Rotors:     strings.Split(*wheels, ","),
+			Reflectors: strings.Split(*reflectors, ","),
+			Rings:      *rings,
+			Steckers:   *steckers,
+			Candidates: *candidates,
+			Strategy:   s,
+			Scorer:     sc,
+			Results:    *numResults,
+		}
+		text, err := message()
+		return text, opts, err
+	}
+}
+
+// Adds the flags for how to run a search, the returned function applies them.
+func runFlags(flags *flag.FlagSet) func(*crack.Options) {
+	workers := flags.Int("workers", runtime.GOMAXPROCS(0), "The number of configurations to try at once.")
+	progress := flags.Bool("progress", false, "Report progress on stderr.")
+	checkpoint := flags.String("checkpoint", "", "Save the search to this file, and resume from it if it exists.")
+	checkpointInterval := flags.Duration("checkpoint-interval", time.Minute, "How often to save the checkpoint.")
+	return func(opts *crack.Options) {
+		opts.Workers = *workers
+		opts.Checkpoint = *checkpoint
+		opts.CheckpointInterval = *checkpointInterval
+		if *progress {
+			opts.Progress = func(p crack.Progress) { fmt.Fprintln(os.Stderr, p) }
+		}
+	}
+}
+
+// With a checkpoint, stop cleanly on ^C so the search can be resumed.
+func interruptible(opts crack.Options) (context.Context, context.CancelFunc) {
+	if opts.Checkpoint == "" {
+		return context.WithCancel(context.Background())
+	}
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+func crackMessage(args []string, in io.Reader, out io.Writer) error {
+	flags := newFlagSet("crack")
+	search := searchFlags(flags, in)
+	apply := runFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	message, opts, err := search()
+	if err != nil {
+		return err
+	}
+	apply(&opts)
+	ctx, stop := interruptible(opts)
+	defer stop()
+
+	results, err := crack.Run(ctx, message, opts)
+	if err == context.Canceled {
+		fmt.Fprintf(out, "Interrupted, resume with --checkpoint=%s. The best results so far:\n", opts.Checkpoint)
+	} else if err != nil {
+		return err
+	}
+	printResults(out, results)
+	return nil
+}
+
+func printResults(out io.Writer, results []crack.Result) {
+	for _, r := range results {
+		fmt.Fprintf(out, "%f %s\n%s\n", r.Score, r.Plaintext, r.Key)
+	}
+}
+
+func keygen(args []string, in io.Reader, out io.Writer) error {
+	flags := newFlagSet("keygen")
+	seed := flags.Int64("seed", 0, "Seed for the random numbers, 0 for the time.")
+	asJSON := flags.Bool("json", false, "Print the key as JSON.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	k := enigma.RandomKey(rand.New(rand.NewSource(*seed)))
+	if *asJSON {
+		return json.NewEncoder(out).Encode(k)
+	}
+	_, err := fmt.Fprintf(out, "--reflector=%s --rotors=%s --rings=%s --positions=%s --plugboard=\"%s\"\n",
+		k.Reflector, strings.Join(k.Rotors, ","), k.Rings, k.Positions, k.Plugboard)
+	return err
+}
+
+func info(args []string, in io.Reader, out io.Writer) error {
+	flags := newFlagSet("info")
+	key := keyFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	k := key()
+	m, err := k.NewMachine()
+	if err != nil {
+		return err
+	}
+	tail, period := m.Period()
+	fmt.Fprintf(out, "Key:        %s\n", k)
+	fmt.Fprintf(out, "%s\n", m)
+	fmt.Fprintf(out, "Period:     %d key presses", period)
+	if tail > 0 {
+		fmt.Fprintf(out, " after %d to reach the cycle", tail)
+	}
+	fmt.Fprintf(out, "\nUnreachable positions: %d\n", len(m.UnreachablePositions()))
+	_, err = fmt.Fprintf(out, "Next key press: %s\n", m.Permutation())
+	return err
+}
+
+func interactive(args []string, in io.Reader, out io.Writer) error {
+	f, ok := in.(*os.File)
+	if !ok {
+		return errors.New("interactive needs a terminal")
+	}
+	return runInteractive(f, out)
+}
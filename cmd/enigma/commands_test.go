@@ -0,0 +1,298 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+// Runs the command line and returns what it wrote.
+func runArgs(t *testing.T, input string, args ...string) string {
+	var out bytes.Buffer
+	if err := runCommand(args, strings.NewReader(input), &out); err != nil {
+		t.Fatalf("enigma %s failed: %s", strings.Join(args, " "), err)
+	}
+	return out.String()
+}
+
+func TestEncryptCommand(t *testing.T) {
+	out := runArgs(t, "aaaaa\n", "encrypt", "--rings=BBB")
+	if out != "EWTYX\n" {
+		t.Errorf("Expected EWTYX, got %s", out)
+	}
+
+	out = runArgs(t, "EWTYX", "decrypt", "--rings=BBB")
+	if out != "AAAAA\n" {
+		t.Errorf("Expected AAAAA, got %s", out)
+	}
+
+	key := []string{"--reflector=C", "--rotors=V,VIII,II", "--rings=QEW",
+		"--positions=GHJ", "--plugboard=AB XY"}
+	encrypted := runArgs(t, "Angriff um 0600 Uhr.", append([]string{"encrypt", "--convention=german"}, key...)...)
+	out = runArgs(t, encrypted, append([]string{"decrypt", "--convention=german"}, key...)...)
+	if out != "ANGRIFF UM 0600 UHR\n" {
+		t.Errorf("Expected ANGRIFF UM 0600 UHR, got %s", out)
+	}
+}
+
+func TestEncryptCommandFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "enigma")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	in, out := filepath.Join(dir, "in.txt"), filepath.Join(dir, "out.txt")
+	ioutil.WriteFile(in, []byte("AAAAA\n"), 0644)
+	if s := runArgs(t, "", "encrypt", "--in="+in, "--out="+out); s != "" {
+		t.Errorf("Expected no output, got %s", s)
+	}
+	b, _ := ioutil.ReadFile(out)
+	if string(b) != "BDZGO\n" {
+		t.Errorf("Expected BDZGO, got %s", string(b))
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	invalid := [][]string{
+		{},
+		{"unknown"},
+		{"encrypt", "--rotors=I,II"},
+		{"encrypt", "--convention=klingon"},
+		{"encrypt", "--in=/does/not/exist"},
+		{"crack", "--message=NOT A MESSAGE"},
+		{"crack", "--message=ABC", "--strategy=guess"},
+		{"crack", "--message=ABC", "--scorer=vibes"},
+		{"crack", "--message=ABC", "--language=klingon"},
+		{"crack", "--message=ABC", "--language=german", "--scorer=words"},
+		{"crack", "--message=ABC", "--steckers=14"},
+		{"crack", "--message=ABC", "--in=-"},
+		{"split", "--message=ABC", "--in=-", "--shards=2", "--dir=" + t.TempDir()},
+		{"crib", "--message=ABC", "--in=-", "--crib=A"},
+		{"bombe", "--message=ABC", "--in=-", "--crib=BCA"},
+		{"menu", "--message=ABC", "--in=-", "--crib=BCA"},
+		{"crib", "--message=ABC"},
+		{"crib", "--message=ABC", "--crib=ABCD"},
+		{"bombe", "--message=ABC"},
+		{"bombe", "--message=ABC", "--crib=CBA"},
+		{"bombe", "--message=ABC", "--crib=BCA", "--wheels=I,II"},
+		{"menu", "--message=ABC"},
+		{"menu", "--message=ABC", "--crib=BCA", "--format=png"},
+		{"rejewski", "--in=/does/not/exist"},
+		{"zygalski", "--in=/does/not/exist"},
+		{"banburismus", "--in=/does/not/exist"},
+		{"banburismus", "--language=klingon"},
+		{"info", "--plugboard=AA"},
+		{"serve", "--max-jobs=0"},
+		{"keygen", "--unknown"},
+	}
+	for _, args := range invalid {
+		var out bytes.Buffer
+		if err := runCommand(args, strings.NewReader(""), &out); err == nil {
+			t.Errorf("Expected enigma %s to fail", strings.Join(args, " "))
+		}
+	}
+}
+
+func TestKeygenCommand(t *testing.T) {
+	out := runArgs(t, "", "keygen", "--seed=7")
+	if out != runArgs(t, "", "keygen", "--seed=7") {
+		t.Errorf("Expected the same key from the same seed")
+	}
+
+	// The key is printed as flags that the other commands accept.
+	parts := strings.SplitN(strings.TrimSpace(out), " --plugboard=", 2)
+	args := append(strings.Fields(parts[0]), "--plugboard="+strings.Trim(parts[1], "\""))
+	runArgs(t, "", append([]string{"info"}, args...)...)
+
+	var k enigma.Key
+	if err := json.Unmarshal([]byte(runArgs(t, "", "keygen", "--json")), &k); err != nil {
+		t.Fatalf("Invalid JSON: %s", err)
+	}
+	if err := k.Validate(); err != nil {
+		t.Errorf("Expected a valid key: %s", err)
+	}
+}
+
+func TestInfoCommand(t *testing.T) {
+	out := runArgs(t, "", "info", "--positions=AEA")
+	for _, expected := range []string{"Key:        B I-II-III AAA AEA",
+		"Period:     16900 key presses after 1 to reach the cycle",
+		"Unreachable positions: 650"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out)
+		}
+	}
+}
+
+func TestCrackCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping full search in short mode.")
+	}
+
+	out := runArgs(t, "", "crack", "--message=BDZGO", "--results=1")
+	if len(strings.Split(strings.TrimSpace(out), "\n")) != 2 {
+		t.Errorf("Expected a single result, got:\n%s", out)
+	}
+
+	// Several keys score the same for such a short message, so only compare
+	// the scores.
+	legacy := runArgs(t, "BDZGO", "--in=-", "--results=1")
+	if strings.Fields(out)[0] != strings.Fields(legacy)[0] {
+		t.Errorf("Expected crack to be the default command, got:\n%s", legacy)
+	}
+}
+
+func TestCribCommand(t *testing.T) {
+	out := runArgs(t, "abcabc\n", "crib", "--in=-", "--crib=xa")
+	expected := "Offset 0:\nABCABC\nXA\n\nOffset 1:\nABCABC\n XA\n\nOffset 3:\nABCABC\n   XA\n\n" +
+		"Offset 4:\nABCABC\n    XA\n\n4 of 5 offsets possible\n"
+	if out != expected {
+		t.Errorf("Expected %q, got %q", expected, out)
+	}
+}
+
+func TestBombeCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping full search in short mode.")
+	}
+
+	// Encrypted with B II-I-III QMW and the plugs AZ EK HN QT RW.
+	out := runArgs(t, "", "bombe", "--message=INATBIDWFUSQOITPHQZUGWETK", "--crib=WETTERVORHERSAGEBISKAYA", "--offset=2")
+	if !strings.Contains(out, "B II-I-III QMW E/K") {
+		t.Errorf("Expected a stop at B II-I-III QMW, got:\n%s", out)
+	}
+}
+
+func TestMenuCommand(t *testing.T) {
+	args := []string{"menu", "--message=INATBIDWFUSQOITPHQZUGWETK", "--crib=WETTERVORHERSAGEBISKAYA",
+		"--offset=2", "--span=16"}
+	out := runArgs(t, "", args...)
+	if !strings.HasPrefix(out, "Menu:  ") || !strings.Contains(out, "false stops per wheel order") {
+		t.Errorf("Expected a menu, got:\n%s", out)
+	}
+
+	var c struct {
+		Menu struct {
+			Edges []string `json:"edges"`
+		} `json:"menu"`
+		Quality struct {
+			Span int `json:"span"`
+		} `json:"quality"`
+	}
+	if err := json.Unmarshal([]byte(runArgs(t, "", append(args, "--format=json")...)), &c); err != nil {
+		t.Fatalf("Invalid JSON: %s", err)
+	}
+	if len(c.Menu.Edges) == 0 || c.Quality.Span > 16 {
+		t.Errorf("Expected a menu covering at most 16 positions, got %+v", c)
+	}
+
+	if out := runArgs(t, "", append(args, "--format=dot")...); !strings.HasPrefix(out, "graph menu {") {
+		t.Errorf("Expected a graph, got:\n%s", out)
+	}
+}
+
+func TestRejewskiCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping building a catalog in short mode.")
+	}
+	dir, err := ioutil.TempDir("", "enigma")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Every message key from AAA to ZZZ sent on one day.
+	k := enigma.Key{Reflector: "B", Rotors: []string{"II", "III", "I"}, Positions: "MQD", Plugboard: "AF KR"}
+	var indicators []string
+	for _, c := range enigma.LETTERS {
+		m, _ := k.NewMachine()
+		key := strings.Repeat(string(c), 3)
+		indicators = append(indicators, m.Encrypt(key+key))
+	}
+	out := runArgs(t, strings.Join(indicators, "\n"), "rejewski", "--catalog="+filepath.Join(dir, "catalog.json"))
+	if !strings.Contains(out, "\nB II-III-I AAA MQD\n") {
+		t.Errorf("Expected B II-III-I AAA MQD, got:\n%s", out)
+	}
+}
+
+func TestZygalskiCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping drawing every sheet in short mode.")
+	}
+	dir, err := ioutil.TempDir("", "enigma")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Indicators of messages that don't move the middle rotor, until 20 are females.
+	k := enigma.Key{Reflector: "B", Rotors: []string{"V", "II", "IV"}, Rings: "CMT", Plugboard: "AQ BJ"}
+	var lines []string
+	for i, females := 0, 0; females < 20; i++ {
+		k.Positions = string([]rune{'A' + rune(i*7%26), 'A' + rune(i*11%26), 'A' + rune(i*5%26)})
+		key := string([]rune{'A' + rune(i*3%26), 'A' + rune(i*17%26), 'A' + rune(i*13%26)})
+		m, _ := k.NewMachine()
+		indicator := m.Encrypt(key + key)
+		if m.Positions()[:2] != k.Positions[:2] {
+			continue
+		}
+		for j := 0; j < 3; j++ {
+			if indicator[j] == indicator[j+3] {
+				females++
+			}
+		}
+		lines = append(lines, k.Positions+" "+indicator)
+	}
+
+	sheets := filepath.Join(dir, "sheets")
+	out := runArgs(t, strings.Join(lines, "\n"), "zygalski", "--wheels=II,IV,V", "--sheets="+sheets)
+	if !strings.Contains(out, "B V-II-IV CMT\n") {
+		t.Errorf("Expected B V-II-IV CMT, got:\n%s", out)
+	}
+	if files, _ := ioutil.ReadDir(sheets); len(files) != 6*26 {
+		t.Errorf("Expected %d sheets, got %d", 6*26, len(files))
+	}
+}
+
+func TestBanburismusCommand(t *testing.T) {
+	text := "VONXBDUXANXFLOTTENCHEFXWETTERBERICHTXNORDSEEXWINDXSTAERKEXVIERXAUSXWESTXSICHTXGUTX" +
+		"SEEGANGXDREIXLUFTDRUCKXFALLENDXUBOOTXMELDETXGELEITZUGXINXQUADRATXACHTXSIEBENXKURSX" +
+		"NORDOSTXFAHRTXZEHNXSEEMEILENXZERSTOERERXBEGLEITENXANGRIFFXBEIXMORGENGRAUENXGEPLANTX" +
+		"FUNKSTILLEXEINHALTENXBISXZUMXANGRIFFXTREIBSTOFFXREICHTXFUERXVIERXTAGEXERBITTEXNEUEX" +
+		"BEFEHLEXWETTERXVERSCHLECHTERTXSICHXREGENXUNDXNEBELXIMXNORDENXSTURMWARNUNGXFUERXDIEX" +
+		"DEUTSCHEXBUCHTXHAFENXWILHELMSHAVENXMELDETXMINENXIMXFAHRWASSERXSCHIFFEXWARTENXAUFXRAEU" +
+		"MUNGXDURCHXMINENSUCHERXKOMMANDANTXERWARTETXBERICHTXUEBERXLAGEXUNDXVERLUSTEXHEUTEXABEN"
+	k := enigma.Key{Reflector: "B", Rotors: []string{"II", "IV", "I"}}
+	var lines []string
+	for i, s := range []string{"VFA", "VFD", "VFH", "VFL", "VFN"} {
+		k.Positions = s
+		m, _ := k.NewMachine()
+		lines = append(lines, s+" "+m.Encrypt(text[60*i:60*i+300]))
+	}
+	out := runArgs(t, strings.Join(lines, "\n"), "banburismus")
+	if !strings.Contains(out, "Chains:    A--D---H---L-N\n") {
+		t.Errorf("Expected the chain A--D---H---L-N, got:\n%s", out)
+	}
+}
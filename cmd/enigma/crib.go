@@ -0,0 +1,58 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mww/enigma-go/crib"
+)
+
+// Shows where a crib can lie under a message.
+func cribPositions(args []string, in io.Reader, out io.Writer) error {
+	flags := newFlagSet("crib")
+	message := messageFlags(flags, in, "The message to place the crib under.")
+	word := flags.String("crib", "", "The probable plaintext, e.g. WETTERVORHERSAGE.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *word == "" {
+		return errors.New("--crib is required")
+	}
+
+	text, err := message()
+	if err != nil {
+		return err
+	}
+	*word = strings.ToUpper(*word)
+	positions, err := crib.Positions(text, *word)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range positions {
+		shown, err := crib.Show(text, *word, p)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Offset %d:\n%s\n", p, shown)
+	}
+	_, err = fmt.Fprintf(out, "%d of %d offsets possible\n", len(positions), len(text)-len(*word)+1)
+	return err
+}
@@ -0,0 +1,153 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/mww/enigma-go/crack"
+)
+
+/*
+	A search can be spread over several processes, or machines sharing a
+	directory, with files as the only transport:
+
+		enigma split --shards=4 --dir=jobs --message=...
+		enigma work --job=jobs/job-0.json --out=jobs/results-0.json   (once per job)
+		enigma merge jobs/results-*.json
+
+	With --steckers the results can differ from one crack run, see
+	crack.Split.
+*/
+
+func split(args []string, in io.Reader, out io.Writer) error {
+	flags := newFlagSet("split")
+	search := searchFlags(flags, in)
+	shards := flags.Int("shards", 0, "The number of jobs to split the search into.")
+	dir := flags.String("dir", ".", "The directory to write the jobs to.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	message, opts, err := search()
+	if err != nil {
+		return err
+	}
+	jobs, err := crack.Split(message, opts, *shards)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return err
+	}
+	for i, j := range jobs {
+		// The scorer is passed on by name, or the model by its file.
+		j.Scorer = flags.Lookup("scorer").Value.String()
+		j.Model = flags.Lookup("model").Value.String()
+		j.Language = flags.Lookup("language").Value.String()
+		path := filepath.Join(*dir, fmt.Sprintf("job-%d.json", i))
+		if err := writeJSONFile(path, j); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, path)
+	}
+	return nil
+}
+
+func work(args []string, in io.Reader, out io.Writer) error {
+	flags := newFlagSet("work")
+	jobFile := flags.String("job", "", "The job to run, - for stdin.")
+	outFile := flags.String("out", "", "Write the results to this file instead of stdout.")
+	apply := runFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *jobFile == "" {
+		return errors.New("--job is required")
+	}
+
+	text, err := readInput(*jobFile, in)
+	if err != nil {
+		return err
+	}
+	var j crack.Job
+	if err := json.Unmarshal([]byte(text), &j); err != nil {
+		return fmt.Errorf("reading %s: %s", *jobFile, err)
+	}
+
+	var opts crack.Options
+	apply(&opts)
+	ctx, stop := interruptible(opts)
+	defer stop()
+	results, err := crack.RunJob(ctx, j, opts)
+	if err != nil {
+		return err
+	}
+
+	if *outFile == "" {
+		return json.NewEncoder(out).Encode(results)
+	}
+	return writeJSONFile(*outFile, results)
+}
+
+func merge(args []string, in io.Reader, out io.Writer) error {
+	flags := newFlagSet("merge")
+	numResults := flags.Int("results", 3, "The number of results to display")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("no results files to merge")
+	}
+
+	var all []crack.JobResults
+	for _, path := range flags.Args() {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var r crack.JobResults
+		if err := json.Unmarshal(b, &r); err != nil {
+			return fmt.Errorf("reading %s: %s", path, err)
+		}
+		all = append(all, r)
+	}
+	results, err := crack.Merge(*numResults, all...)
+	if err != nil {
+		return err
+	}
+	printResults(out, results)
+	return nil
+}
+
+// Writes v as JSON, through a temporary file so readers never see half of it.
+func writeJSONFile(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(b, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,75 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitWorkMerge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	text := "ITWASTHEBESTOFTIMESITWASTHEWORSTOFTIMESITWASTHEAGEOFWISDOM" +
+		"ITWASTHEAGEOFFOOLISHNESSITWASTHEEPOCHOFBELIEFITWASTHEEPOCHOFINCREDULITY"
+	message := strings.TrimSpace(runArgs(t, text, "encrypt", "--rotors=III,I,II", "--positions=QEV"))
+
+	jobs := strings.Fields(runArgs(t, "", "split", "--shards=3", "--dir="+dir,
+		"--message="+message, "--reflectors=B", "--results=1"))
+	if len(jobs) != 3 {
+		t.Fatalf("Expected 3 jobs, got %v", jobs)
+	}
+
+	var results []string
+	for i, job := range jobs {
+		r := filepath.Join(dir, "results-"+string(rune('0'+i))+".json")
+		runArgs(t, "", "work", "--job="+job, "--out="+r, "--workers=2")
+		results = append(results, r)
+	}
+
+	out := runArgs(t, "", append([]string{"merge", "--results=1"}, results...)...)
+	if !strings.Contains(out, text) || !strings.Contains(out, "B III-I-II AAA QEV") {
+		t.Errorf("Expected the merged results to find the key, got %s", out)
+	}
+
+	out = runArgs(t, "", append([]string{"merge", "--results=0"}, results...)...)
+	if !strings.Contains(out, "B III-I-II AAA QEV") {
+		t.Errorf("Expected the default number of results, got %s", out)
+	}
+	if err := runCommand(append([]string{"merge"}, results[:2]...), strings.NewReader(""), ioutil.Discard); err == nil {
+		t.Errorf("Expected an error merging results that don't cover the key space")
+	}
+}
+
+func TestDistributedErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"split", "--shards=0", "--message=ABC"},
+		{"work"},
+		{"work", "--job=does-not-exist.json"},
+		{"merge"},
+	} {
+		if err := runCommand(args, strings.NewReader(""), ioutil.Discard); err == nil {
+			t.Errorf("Expected an error from %v", args)
+		}
+	}
+}
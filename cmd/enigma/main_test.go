@@ -15,7 +15,10 @@
 */
 package main
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestEnigmaRunner(t *testing.T) {
 	if testing.Short() {
@@ -24,8 +27,8 @@ func TestEnigmaRunner(t *testing.T) {
 
 	encrypted := "ZTQBLVXKPBPGAVQBRYDYQEZNKRLMZTMRGBJSQKHDPHHNTNIDLYVFCOKZYYSMJFAHQBTEAVFKOXRPSQX"
 	expected := "THISISASLIGHTLYLONGERTESTSOIHAVETOSEEIFICANKEEPWRITINGALONGERSTRINGTOUSEASINPUT"
-	results := run(&encrypted, 3)
-	if (*results)[0].message != expected {
-		t.Errorf("Expected %s, got %s", expected, (*results)[0].message)
+	results := strings.Split(runArgs(t, "", "--message="+encrypted), "\n")
+	if !strings.HasSuffix(results[0], " "+expected) {
+		t.Errorf("Expected %s, got %s", expected, results[0])
 	}
 }
@@ -0,0 +1,67 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mww/enigma-go/bombe"
+)
+
+// Chooses the best Bombe menu from a crib.
+func chooseMenu(args []string, in io.Reader, out io.Writer) error {
+	flags := newFlagSet("menu")
+	message := messageFlags(flags, in, "The encrypted message.")
+	word := flags.String("crib", "", "The probable plaintext.")
+	offset := flags.Int("offset", 0, "Where the crib lies in the message, from 0.")
+	span := flags.Int("span", 0, "The most positions of the message a menu may cover, 0 for any.")
+	format := flags.String("format", "text", "Write the menu as text, json or dot.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *word == "" {
+		return errors.New("--crib is required")
+	}
+	if *format != "text" && *format != "json" && *format != "dot" {
+		return errors.New("--format must be text, json or dot")
+	}
+
+	text, err := message()
+	if err != nil {
+		return err
+	}
+	choices, err := bombe.Menus(text, *word, *offset, *span)
+	if err != nil {
+		return err
+	}
+	best := choices[0]
+
+	switch *format {
+	case "json":
+		return json.NewEncoder(out).Encode(best)
+	case "dot":
+		return best.Menu.WriteDOT(out)
+	}
+	q := best.Quality
+	_, err = fmt.Fprintf(out, "Menu:  %s\n"+
+		"Test letter %s, %d letters, %d edges, %d loops\n"+
+		"Span %d, %.0f%% chance of a turnover, about %.3g false stops per wheel order\n",
+		best.Menu, q.TestLetter, q.Letters, q.Edges, q.Loops, q.Span, 100*q.Turnover, q.FalseStops)
+	return err
+}
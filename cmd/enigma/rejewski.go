@@ -0,0 +1,67 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mww/enigma-go/rejewski"
+)
+
+// Looks up the daily key of a set of doubled indicators.
+func lookupIndicators(args []string, in io.Reader, out io.Writer) error {
+	flags := newFlagSet("rejewski")
+	inFile := flags.String("in", "-", "Read the indicators from this file, - for stdin.")
+	catalog := flags.String("catalog", "", "Keep the catalog in this file, built the first time.")
+	wheels := flags.String("wheels", "I,II,III",
+		"The rotors to choose the wheel order from, or army for I-V or naval for I-VIII.")
+	reflectors := flags.String("reflectors", "B", "The reflectors to include.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if set, ok := wheelSets[strings.ToLower(*wheels)]; ok {
+		*wheels = set
+	}
+
+	text, err := readInput(*inFile, in)
+	if err != nil {
+		return err
+	}
+	ch, err := rejewski.FromIndicators(strings.Fields(text))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Characteristic: %s\n", ch)
+
+	opts := rejewski.Options{Rotors: strings.Split(*wheels, ","), Reflectors: strings.Split(*reflectors, ",")}
+	var c *rejewski.Catalog
+	if *catalog != "" {
+		c, err = rejewski.Cached(*catalog, opts)
+	} else {
+		c, err = rejewski.Build(opts)
+	}
+	if err != nil {
+		return err
+	}
+	keys := c.Lookup(ch)
+	for _, k := range keys {
+		fmt.Fprintln(out, k)
+	}
+	_, err = fmt.Fprintf(out, "%d keys\n", len(keys))
+	return err
+}
@@ -0,0 +1,414 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	enigma "github.com/mww/enigma-go"
+	"github.com/mww/enigma-go/crack"
+	"github.com/mww/enigma-go/frequency"
+)
+
+/*
+	The JSON API served by "enigma serve [--addr=HOST:PORT]":
+
+		POST   /encrypt        {"key": KEY, "text": "..."}    -> {"text": "..."}
+		POST   /decrypt        {"key": KEY, "text": "..."}    -> {"text": "..."}
+		POST   /keys/validate  {"key": KEY}                   -> {"valid": true}
+		GET    /keys/random                                   -> {"key": KEY}
+		POST   /jobs           {"message": "...", "results": 3, ...} -> JOB
+		GET    /jobs/ID                                       -> JOB
+		DELETE /jobs/ID                                       -> JOB
+
+	A job can also have the search options of the crack command:
+	"rotors" and "reflectors" as lists of names, "rings", "steckers",
+	"candidates", "strategy", "scorer" and "language". The language must be
+	one of the built in ones, the server doesn't read files for a request.
+
+	JOB is {"id": "1", "status": "running", "tested": N, "total": N,
+	"results": [{"key": KEY, "plaintext": "...", "score": N}, ...]}, where
+	status is one of running, done, cancelled or failed. A cancelled job
+	keeps the best results found before it stopped. Only --max-jobs jobs
+	run at once, POST /jobs returns 503 when that many are running.
+	Finished jobs are forgotten after --keep-jobs.
+
+	KEY is an enigma.Key, e.g. {"reflector": "B", "rotors": ["I", "II", "III"],
+	"rings": "AAA", "positions": "AAA", "plugboard": "AB CD"}. Errors are
+	returned as {"error": "..."} with a 4xx status. Request bodies over
+	maxRequestBytes are refused.
+*/
+
+type textRequest struct {
+	Key  enigma.Key `json:"key"`
+	Text string     `json:"text"`
+}
+
+type textResponse struct {
+	Text string `json:"text"`
+}
+
+type keyRequest struct {
+	Key enigma.Key `json:"key"`
+}
+
+type validateResponse struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+type keyResponse struct {
+	Key enigma.Key `json:"key"`
+}
+
+type jobRequest struct {
+	Message    string   `json:"message"`
+	Results    int      `json:"results"`
+	Rotors     []string `json:"rotors,omitempty"`
+	Reflectors []string `json:"reflectors,omitempty"`
+	Rings      bool     `json:"rings,omitempty"`
+	Steckers   int      `json:"steckers,omitempty"`
+	Candidates int      `json:"candidates,omitempty"`
+	Strategy   string   `json:"strategy,omitempty"`
+	Scorer     string   `json:"scorer,omitempty"`
+	Language   string   `json:"language,omitempty"`
+}
+
+type jobResponse struct {
+	ID      string         `json:"id"`
+	Status  string         `json:"status"`
+	Tested  int64          `json:"tested"`
+	Total   int64          `json:"total"`
+	Results []crack.Result `json:"results,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+const (
+	jobRunning   = "running"
+	jobDone      = "done"
+	jobCancelled = "cancelled"
+	jobFailed    = "failed"
+)
+
+const (
+	maxJobResults   = 100
+	maxRequestBytes = 1 << 20
+)
+
+const (
+	defaultMaxJobs  = 4
+	defaultKeepJobs = time.Hour
+)
+
+// A cracking job running in the background.
+type job struct {
+	id       string
+	status   string
+	progress crack.Progress
+	results  []crack.Result
+	err      error
+	cancel   context.CancelFunc
+	finished time.Time // When the search stopped, zero while it runs.
+}
+
+type server struct {
+	mux *http.ServeMux
+
+	maxJobs  int           // The most searches that may run at once.
+	keepJobs time.Duration // How long finished jobs can still be polled.
+
+	// The clock and the search run for each job, which tests replace.
+	now    func() time.Time
+	search func(ctx context.Context, ciphertext string, opts crack.Options) ([]crack.Result, error)
+
+	mu      sync.Mutex
+	random  *rand.Rand
+	jobs    map[string]*job
+	nextID  int
+	running int
+}
+
+func newServer() *server {
+	s := server{
+		mux:      http.NewServeMux(),
+		maxJobs:  defaultMaxJobs,
+		keepJobs: defaultKeepJobs,
+		now:      time.Now,
+		search:   crack.Run,
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		jobs:     make(map[string]*job),
+	}
+	s.mux.HandleFunc("/encrypt", s.handleText)
+	s.mux.HandleFunc("/decrypt", s.handleText)
+	s.mux.HandleFunc("/keys/validate", s.handleValidate)
+	s.mux.HandleFunc("/keys/random", s.handleRandomKey)
+	s.mux.HandleFunc("/jobs", s.handleNewJob)
+	s.mux.HandleFunc("/jobs/", s.handleJob)
+	return &s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func serve(args []string, in io.Reader, out io.Writer) error {
+	flags := newFlagSet("serve")
+	addr := flags.String("addr", "localhost:8080", "The address to listen on.")
+	maxJobs := flags.Int("max-jobs", defaultMaxJobs, "The most cracking jobs to run at once.")
+	keepJobs := flags.Duration("keep-jobs", defaultKeepJobs, "How long to keep finished jobs.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *maxJobs < 1 {
+		return fmt.Errorf("--max-jobs must be at least 1, got %d", *maxJobs)
+	}
+
+	s := newServer()
+	s.maxJobs, s.keepJobs = *maxJobs, *keepJobs
+	fmt.Fprintf(out, "Listening on http://%s/\n", *addr)
+	return http.ListenAndServe(*addr, s)
+}
+
+// Encrypting and decrypting are the same operation.
+func (s *server) handleText(w http.ResponseWriter, r *http.Request) {
+	var req textRequest
+	if !decode(w, r, "POST", &req) {
+		return
+	}
+	m, err := req.Key.NewMachine()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, textResponse{m.Encrypt(req.Text)})
+}
+
+func (s *server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	var req keyRequest
+	if !decode(w, r, "POST", &req) {
+		return
+	}
+	if err := req.Key.Validate(); err != nil {
+		writeJSON(w, http.StatusOK, validateResponse{false, err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, validateResponse{Valid: true})
+}
+
+func (s *server) handleRandomKey(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, "GET") {
+		return
+	}
+	s.mu.Lock()
+	k := enigma.RandomKey(s.random)
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, keyResponse{k})
+}
+
+func (s *server) handleNewJob(w http.ResponseWriter, r *http.Request) {
+	var req jobRequest
+	if !decode(w, r, "POST", &req) {
+		return
+	}
+	req.Message = strings.ToUpper(req.Message)
+	if len(req.Message) < 1 || strings.IndexFunc(req.Message, notLetter) >= 0 {
+		writeError(w, http.StatusBadRequest, errors.New("message must only contain the letters A-Z"))
+		return
+	}
+	if req.Results < 1 || req.Results > maxJobResults {
+		req.Results = 3
+	}
+	opts, err := searchOptions(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	s.expire()
+	if s.running >= s.maxJobs {
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable,
+			fmt.Errorf("%d jobs are already running, try again later", s.maxJobs))
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.nextID++
+	s.running++
+	j := &job{id: strconv.Itoa(s.nextID), status: jobRunning, cancel: cancel}
+	s.jobs[j.id] = j
+	s.mu.Unlock()
+
+	opts.Progress = func(p crack.Progress) {
+		s.mu.Lock()
+		j.progress = p
+		s.mu.Unlock()
+	}
+	go func() {
+		results, err := s.search(ctx, req.Message, opts)
+		cancel()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.running--
+		j.finished = s.now()
+		j.results = results
+		if j.status == jobRunning {
+			j.status = jobDone
+			if err != nil {
+				j.status, j.err = jobFailed, err
+			}
+		}
+	}()
+
+	writeJSON(w, http.StatusAccepted, s.describe(j))
+}
+
+/*
+	The search a job request asks for, set up the way the crack command sets
+	up its flags.
+*/
+func searchOptions(req jobRequest) (crack.Options, error) {
+	if req.Language != "" {
+		if _, err := frequency.LanguageByName(req.Language); err != nil {
+			return crack.Options{}, err
+		}
+		// Other languages only have the scorers that count letters.
+		if req.Scorer == "" {
+			req.Scorer = "unigram"
+		}
+	}
+	j := crack.Job{
+		Ciphertext: req.Message,
+		Rotors:     req.Rotors,
+		Reflectors: req.Reflectors,
+		Rings:      req.Rings,
+		Steckers:   req.Steckers,
+		Candidates: req.Candidates,
+		Strategy:   req.Strategy,
+		Scorer:     req.Scorer,
+		Language:   req.Language,
+		Results:    req.Results,
+	}
+	return j.Options(crack.Options{})
+}
+
+// Polls (GET) or cancels (DELETE) a job.
+func (s *server) handleJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	s.mu.Lock()
+	s.expire()
+	j, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no job %q", id))
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "DELETE":
+		s.mu.Lock()
+		if j.status == jobRunning {
+			j.status = jobCancelled
+			j.cancel()
+		}
+		s.mu.Unlock()
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s not allowed", r.Method))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.describe(j))
+}
+
+// Forgets jobs that finished more than keepJobs ago. s.mu must be held.
+func (s *server) expire() {
+	now := s.now()
+	for id, j := range s.jobs {
+		if !j.finished.IsZero() && now.Sub(j.finished) > s.keepJobs {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+func (s *server) describe(j *job) jobResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := jobResponse{
+		ID:      j.id,
+		Status:  j.status,
+		Tested:  j.progress.Tested,
+		Total:   j.progress.Total,
+		Results: j.results,
+	}
+	if j.err != nil {
+		resp.Error = j.err.Error()
+	}
+	return resp
+}
+
+func notLetter(r rune) bool {
+	return r < 'A' || r > 'Z'
+}
+
+func checkMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s not allowed", r.Method))
+		return false
+	}
+	return true
+}
+
+// Reads the JSON request body into v, writing an error response on failure.
+func decode(w http.ResponseWriter, r *http.Request, method string, v interface{}) bool {
+	if !checkMethod(w, r, method) {
+		return false
+	}
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(v)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge,
+			fmt.Errorf("the request is over %d bytes", tooLarge.Limit))
+		return false
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %s", err))
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,276 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	enigma "github.com/mww/enigma-go"
+	"github.com/mww/enigma-go/crack"
+)
+
+// Sends a request with an optional JSON body and decodes the JSON response.
+func call(t *testing.T, ts *httptest.Server, method, path string, body, response interface{}) int {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, ts.URL+path, &buf)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		t.Fatalf("Invalid response: %s", err)
+	}
+	return resp.StatusCode
+}
+
+var testKey = enigma.Key{Reflector: "B", Rotors: []string{"I", "II", "III"}, Rings: "BBB"}
+
+func TestServeEncryptDecrypt(t *testing.T) {
+	ts := httptest.NewServer(newServer())
+	defer ts.Close()
+
+	var resp textResponse
+	if status := call(t, ts, "POST", "/encrypt", textRequest{testKey, "aaaaa"}, &resp); status != 200 {
+		t.Errorf("Expected 200, got %d", status)
+	}
+	if resp.Text != "EWTYX" {
+		t.Errorf("Expected EWTYX, got %s", resp.Text)
+	}
+
+	call(t, ts, "POST", "/decrypt", textRequest{testKey, "EWTYX"}, &resp)
+	if resp.Text != "AAAAA" {
+		t.Errorf("Expected AAAAA, got %s", resp.Text)
+	}
+
+	var e errorResponse
+	bad := textRequest{enigma.Key{Reflector: "Q"}, "AAA"}
+	if status := call(t, ts, "POST", "/encrypt", bad, &e); status != 400 || e.Error == "" {
+		t.Errorf("Expected 400 with an error, got %d %s", status, e.Error)
+	}
+	if status := call(t, ts, "GET", "/encrypt", nil, &e); status != 405 {
+		t.Errorf("Expected 405, got %d", status)
+	}
+}
+
+func TestServeKeys(t *testing.T) {
+	ts := httptest.NewServer(newServer())
+	defer ts.Close()
+
+	var random keyResponse
+	if status := call(t, ts, "GET", "/keys/random", nil, &random); status != 200 {
+		t.Errorf("Expected 200, got %d", status)
+	}
+
+	var v validateResponse
+	call(t, ts, "POST", "/keys/validate", keyRequest{random.Key}, &v)
+	if !v.Valid {
+		t.Errorf("Expected %s to be valid: %s", random.Key, v.Error)
+	}
+
+	invalid := enigma.Key{Reflector: "B", Rotors: []string{"I", "I", "II"}}
+	call(t, ts, "POST", "/keys/validate", keyRequest{invalid}, &v)
+	if v.Valid || v.Error == "" {
+		t.Errorf("Expected %s to be invalid", invalid)
+	}
+}
+
+func waitForJob(t *testing.T, ts *httptest.Server, id string) jobResponse {
+	var j jobResponse
+	for i := 0; i < 600; i++ {
+		call(t, ts, "GET", "/jobs/"+id, nil, &j)
+		if j.Status != jobRunning {
+			return j
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish", id)
+	return j
+}
+
+func TestServeJobs(t *testing.T) {
+	ts := httptest.NewServer(newServer())
+	defer ts.Close()
+
+	var j jobResponse
+	if status := call(t, ts, "POST", "/jobs", jobRequest{Message: "BDZGO", Results: 2}, &j); status != 202 {
+		t.Errorf("Expected 202, got %d", status)
+	}
+	if j.Status != jobRunning {
+		t.Errorf("Expected a running job, got %s", j.Status)
+	}
+
+	j = waitForJob(t, ts, j.ID)
+	if j.Status != jobDone || len(j.Results) != 2 {
+		t.Errorf("Expected 2 results from a finished job, got %s %v", j.Status, j.Results)
+	}
+
+	var e errorResponse
+	if status := call(t, ts, "GET", "/jobs/1000", nil, &e); status != 404 {
+		t.Errorf("Expected 404, got %d", status)
+	}
+	if status := call(t, ts, "POST", "/jobs", jobRequest{Message: "NOT A MESSAGE", Results: 2}, &e); status != 400 {
+		t.Errorf("Expected 400, got %d", status)
+	}
+}
+
+func TestServeCancelJob(t *testing.T) {
+	// The search only stops when it is cancelled, however fast crack gets.
+	s := newServer()
+	s.search = blockingSearch
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var j jobResponse
+	call(t, ts, "POST", "/jobs", jobRequest{Message: "BDZGO", Results: 3}, &j)
+	if j.Status != jobRunning {
+		t.Errorf("Expected a running job, got %s", j.Status)
+	}
+	call(t, ts, "DELETE", "/jobs/"+j.ID, nil, &j)
+	if j.Status != jobCancelled {
+		t.Errorf("Expected a cancelled job, got %s", j.Status)
+	}
+	waitForSearches(t, s)
+	if call(t, ts, "GET", "/jobs/"+j.ID, nil, &j); j.Status != jobCancelled {
+		t.Errorf("Expected the job to stay cancelled once its search stopped, got %s", j.Status)
+	}
+}
+
+// Waits for every job's search to return.
+func waitForSearches(t *testing.T, s *server) {
+	for i := 0; i < 600; i++ {
+		s.mu.Lock()
+		running := s.running
+		s.mu.Unlock()
+		if running == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("The searches did not stop")
+}
+
+// A search that runs until it is cancelled.
+func blockingSearch(ctx context.Context, ciphertext string, opts crack.Options) ([]crack.Result, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestServeJobOptions(t *testing.T) {
+	s := newServer()
+	searched := make(chan crack.Options, 1)
+	s.search = func(ctx context.Context, ciphertext string, opts crack.Options) ([]crack.Result, error) {
+		searched <- opts
+		return nil, nil
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var j jobResponse
+	req := jobRequest{Message: "BDZGO", Results: 5, Rotors: []string{"I", "II", "III", "IV", "V"},
+		Reflectors: []string{"B"}, Rings: true, Steckers: 6, Candidates: 20, Strategy: "anneal",
+		Scorer: "bigram"}
+	if status := call(t, ts, "POST", "/jobs", req, &j); status != 202 {
+		t.Fatalf("Expected 202, got %d", status)
+	}
+	opts := <-searched
+	if len(opts.Rotors) != 5 || len(opts.Reflectors) != 1 || !opts.Rings || opts.Steckers != 6 ||
+		opts.Candidates != 20 || opts.Results != 5 || opts.Strategy.Name() != "anneal" || opts.Scorer == nil {
+		t.Errorf("Expected the search to have the job's options, got %+v", opts)
+	}
+
+	var e errorResponse
+	for _, bad := range []jobRequest{
+		{Message: "BDZGO", Scorer: "nonsense"},
+		{Message: "BDZGO", Strategy: "nonsense"},
+		{Message: "BDZGO", Language: "/etc/passwd"},
+	} {
+		if status := call(t, ts, "POST", "/jobs", bad, &e); status != 400 || e.Error == "" {
+			t.Errorf("Expected 400 for %+v, got %d %s", bad, status, e.Error)
+		}
+	}
+
+	huge := jobRequest{Message: strings.Repeat("A", maxRequestBytes)}
+	if status := call(t, ts, "POST", "/jobs", huge, &e); status != 413 {
+		t.Errorf("Expected 413 for a huge request, got %d %s", status, e.Error)
+	}
+}
+
+func TestServeJobLimit(t *testing.T) {
+	s := newServer()
+	s.maxJobs = 1
+	s.search = blockingSearch
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var j jobResponse
+	if status := call(t, ts, "POST", "/jobs", jobRequest{Message: "BDZGO", Results: 3}, &j); status != 202 {
+		t.Fatalf("Expected 202, got %d", status)
+	}
+	var e errorResponse
+	if status := call(t, ts, "POST", "/jobs", jobRequest{Message: "BDZGO", Results: 3}, &e); status != 503 || e.Error == "" {
+		t.Errorf("Expected 503 with an error, got %d %s", status, e.Error)
+	}
+
+	// Once the first job has stopped another can start.
+	call(t, ts, "DELETE", "/jobs/"+j.ID, nil, &j)
+	waitForSearches(t, s)
+	if status := call(t, ts, "POST", "/jobs", jobRequest{Message: "BDZGO", Results: 3}, &j); status != 202 {
+		t.Errorf("Expected 202 once the first job stopped, got %d", status)
+	}
+}
+
+func TestServeExpireJobs(t *testing.T) {
+	now := time.Date(1940, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newServer()
+	s.now = func() time.Time { return now }
+	s.search = func(ctx context.Context, ciphertext string, opts crack.Options) ([]crack.Result, error) {
+		return nil, nil
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var j jobResponse
+	call(t, ts, "POST", "/jobs", jobRequest{Message: "BDZGO", Results: 3}, &j)
+	if j = waitForJob(t, ts, j.ID); j.Status != jobDone {
+		t.Fatalf("Expected a finished job, got %s", j.Status)
+	}
+
+	s.mu.Lock()
+	now = now.Add(s.keepJobs)
+	s.mu.Unlock()
+	if status := call(t, ts, "GET", "/jobs/"+j.ID, nil, &j); status != 200 {
+		t.Errorf("Expected the job to be kept for %s, got %d", s.keepJobs, status)
+	}
+
+	s.mu.Lock()
+	now = now.Add(time.Second)
+	s.mu.Unlock()
+	var e errorResponse
+	if status := call(t, ts, "GET", "/jobs/"+j.ID, nil, &e); status != 404 {
+		t.Errorf("Expected 404 for an expired job, got %d", status)
+	}
+}
@@ -0,0 +1,292 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+const (
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyBackspace = 8
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// The keyboard and lampboard layout of a German Enigma.
+var lampRows = []string{"QWERTZUIO", "ASDFGHJK", "PYXCVBNML"}
+var lampIndents = []string{"  ", "    ", "  "}
+
+const help = "a-z type   backspace undo   1 2 3 turn rotors   4 5 6 turn rings   " +
+	"= plug/unplug a pair   ctrl-c quit"
+
+/*
+	Holds the state of an interactive session, everything needed to redraw
+	the screen after each key.
+*/
+type simulator struct {
+	machine *enigma.Machine
+	trace   *enigma.Trace
+
+	input, output []rune
+
+	// The rotor positions before each key press, for undo.
+	history []string
+
+	// The letters typed so far after =, while choosing a plug pair.
+	plugging   bool
+	plugLetter rune
+
+	status string
+}
+
+func newSimulator() *simulator {
+	s := simulator{trace: new(enigma.Trace)}
+	s.machine = enigma.NewMachine(enigma.Rotor1(), enigma.Rotor2(), enigma.Rotor3(),
+		enigma.ReflectorB(), 'A', 'A', 'A')
+	s.machine.SetObserver(s.trace)
+	return &s
+}
+
+// Handles a single key, returning false when the user wants to quit.
+func (s *simulator) handleKey(key byte) bool {
+	s.status = ""
+	if key >= 'a' && key <= 'z' {
+		key -= 'a' - 'A'
+	}
+
+	switch {
+	case key == keyCtrlC || key == keyCtrlD:
+		return false
+	case s.plugging && key >= 'A' && key <= 'Z':
+		s.plug(rune(key))
+	case s.plugging:
+		s.plugging = false
+		s.status = "Plug cancelled"
+	case key >= 'A' && key <= 'Z':
+		s.history = append(s.history, s.machine.Positions())
+		s.input = append(s.input, rune(key))
+		s.output = append(s.output, s.machine.Step(rune(key)))
+	case key == keyBackspace || key == keyDelete:
+		s.undo()
+	case key >= '1' && key <= '3':
+		p := []rune(s.machine.Positions())
+		p[key-'1'] = advance(p[key-'1'])
+		s.machine.SetPositions(p[0], p[1], p[2])
+		s.settingsChanged()
+	case key >= '4' && key <= '6':
+		g := []rune(s.machine.Rings())
+		g[key-'4'] = advance(g[key-'4'])
+		s.machine.SetRings(g[0], g[1], g[2])
+		s.settingsChanged()
+	case key == '=':
+		s.plugging, s.plugLetter = true, 0
+		s.status = "Plug: press two letters"
+	}
+	return true
+}
+
+func (s *simulator) undo() {
+	n := len(s.history)
+	if n == 0 {
+		s.status = "Nothing to undo"
+		return
+	}
+	p := s.history[n-1]
+	s.history = s.history[:n-1]
+	s.input, s.output = s.input[:n-1], s.output[:n-1]
+	if n > 1 {
+		// Presses the key before again so its lamp is lit, ending up at p.
+		before := s.history[n-2]
+		s.machine.SetPositions(rune(before[0]), rune(before[1]), rune(before[2]))
+		s.machine.Step(s.input[n-2])
+	} else {
+		s.machine.SetPositions(rune(p[0]), rune(p[1]), rune(p[2]))
+		s.trace.Hops = s.trace.Hops[:0]
+	}
+}
+
+/*
+	Collects the two letters of a plug pair. If either letter is already
+	plugged its existing pair is removed instead.
+*/
+func (s *simulator) plug(letter rune) {
+	if s.plugLetter == 0 {
+		s.plugLetter = letter
+		s.status = fmt.Sprintf("Plug: %c-", letter)
+		return
+	}
+	a, b := s.plugLetter, letter
+	s.plugging, s.plugLetter = false, 0
+
+	var pairs []string
+	removed := false
+	if p := s.machine.Plugboard(); p != nil {
+		for _, pair := range strings.Fields(p.String()) {
+			if strings.ContainsRune(pair, a) || strings.ContainsRune(pair, b) {
+				removed = true
+				continue
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+	if !removed {
+		pairs = append(pairs, string([]rune{a, b}))
+	}
+
+	p, err := enigma.NewPlugboard(strings.Join(pairs, " "))
+	if err != nil {
+		s.status = err.Error()
+		return
+	}
+	s.machine.SetPlugboard(p)
+	s.settingsChanged()
+}
+
+// Key presses made with different settings can't be undone.
+func (s *simulator) settingsChanged() {
+	s.history = s.history[:0]
+	s.input, s.output = s.input[:0], s.output[:0]
+	s.trace.Hops = s.trace.Hops[:0]
+}
+
+func advance(letter rune) rune {
+	return 'A' + (letter-'A'+1)%26
+}
+
+// Draws the whole screen, which works with the terminal in raw mode.
+func (s *simulator) render(w io.Writer) {
+	var buf bytes.Buffer
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&buf, format, args...)
+		buf.WriteString("\r\n")
+	}
+
+	r1, r2, r3 := s.machine.Rotors()
+	positions, rings := s.machine.Positions(), s.machine.Rings()
+	buf.WriteString("\x1b[2J\x1b[H")
+	line("  %s | %s | %s | %s", s.machine.Reflector(), r1, r2, r3)
+	line("")
+	line("  Windows  [ %c ] [ %c ] [ %c ]", positions[0], positions[1], positions[2])
+	line("  Rings      %c     %c     %c", rings[0], rings[1], rings[2])
+	line("")
+
+	lamp := rune(0)
+	if len(s.output) > 0 {
+		lamp = s.trace.Lamp()
+	}
+	for i, row := range lampRows {
+		buf.WriteString(lampIndents[i])
+		for _, l := range row {
+			if l == lamp {
+				fmt.Fprintf(&buf, "\x1b[7m %c \x1b[0m ", l)
+			} else {
+				fmt.Fprintf(&buf, " %c  ", l)
+			}
+		}
+		buf.WriteString("\r\n")
+	}
+	line("")
+
+	plugs := ""
+	if p := s.machine.Plugboard(); p != nil {
+		plugs = p.String()
+	}
+	line("  Plugboard: %s", plugs)
+	line("  In:  %s", string(s.input))
+	line("  Out: %s", string(s.output))
+	line("")
+	line("  %s", s.status)
+	line("  %s", help)
+	w.Write(buf.Bytes())
+}
+
+/*
+	Runs the simulator on the terminal until the user quits. If the terminal
+	can't be put into raw mode each line of keys has to be followed by Enter.
+*/
+func runInteractive(in *os.File, out io.Writer) error {
+	restore, err := makeRaw(in)
+	if err != nil {
+		fmt.Fprintf(out, "Can't read single keys (%s), press Enter after typing.\n", err)
+	} else {
+		defer restore()
+	}
+
+	s := newSimulator()
+	return s.run(bufio.NewReader(in), out)
+}
+
+// Reads keys and redraws the screen after each one until the user quits.
+func (s *simulator) run(reader *bufio.Reader, out io.Writer) error {
+	s.render(out)
+	for {
+		key, err := reader.ReadByte()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if key == keyEscape {
+			if err := skipEscape(reader); err != nil && err != io.EOF {
+				return err
+			}
+			continue
+		}
+		if key == '\n' || key == '\r' {
+			continue
+		}
+		if !s.handleKey(key) {
+			return nil
+		}
+		s.render(out)
+	}
+}
+
+/*
+	Reads the rest of the sequence an arrow or function key sends after
+	ESC, such as ESC [ A or ESC O P, so that none of it is typed. An ESC
+	that arrived on its own is the escape key and ends there.
+*/
+func skipEscape(reader *bufio.Reader) error {
+	if reader.Buffered() == 0 {
+		return nil
+	}
+	b, err := reader.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch b {
+	case '[':
+		// Parameters and intermediate bytes up to a final byte, @ to ~.
+		for {
+			if b, err = reader.ReadByte(); err != nil || (b >= '@' && b <= '~') {
+				return err
+			}
+		}
+	case 'O':
+		_, err = reader.ReadByte()
+	}
+	return err
+}
@@ -0,0 +1,136 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func typeKeys(s *simulator, keys string) {
+	for i := 0; i < len(keys); i++ {
+		s.handleKey(keys[i])
+	}
+}
+
+func TestSimulatorTyping(t *testing.T) {
+	s := newSimulator()
+	typeKeys(s, "apple")
+	if string(s.output) != "BHSDR" {
+		t.Errorf("Expected BHSDR, got %s", string(s.output))
+	}
+	if s.machine.Positions() != "AAF" {
+		t.Errorf("Expected AAF, got %s", s.machine.Positions())
+	}
+
+	var buf bytes.Buffer
+	s.render(&buf)
+	if !strings.Contains(buf.String(), "\x1b[7m R \x1b[0m") {
+		t.Errorf("Expected lamp R to be lit:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "Out: BHSDR") {
+		t.Errorf("Expected the output tape to be shown:\n%s", buf.String())
+	}
+}
+
+func TestSimulatorUndo(t *testing.T) {
+	s := newSimulator()
+	typeKeys(s, "appl\x7f\x7f")
+	if string(s.input) != "AP" || string(s.output) != "BH" {
+		t.Errorf("Expected AP and BH, got %s and %s", string(s.input), string(s.output))
+	}
+	if s.machine.Positions() != "AAC" {
+		t.Errorf("Expected AAC, got %s", s.machine.Positions())
+	}
+
+	typeKeys(s, "pl")
+	if string(s.output) != "BHSD" {
+		t.Errorf("Expected BHSD, got %s", string(s.output))
+	}
+
+	typeKeys(s, "\x7f\x7f\x7f\x7f\x7f")
+	if s.status != "Nothing to undo" || s.machine.Positions() != "AAA" {
+		t.Errorf("Expected to be back at AAA, got %s", s.machine.Positions())
+	}
+}
+
+func TestSimulatorUndoLamp(t *testing.T) {
+	s := newSimulator()
+	typeKeys(s, "app\x7f")
+	if l := s.trace.Lamp(); l != 'H' {
+		t.Errorf("Expected lamp H to be lit after the undo, got %c", l)
+	}
+	if s.machine.Positions() != "AAC" {
+		t.Errorf("Expected AAC, got %s", s.machine.Positions())
+	}
+	typeKeys(s, "\x7f\x7f")
+	if l := s.trace.Lamp(); l != 0 || s.machine.Positions() != "AAA" {
+		t.Errorf("Expected no lamp at AAA, got %c at %s", l, s.machine.Positions())
+	}
+}
+
+func TestSimulatorEscapeSequences(t *testing.T) {
+	s := newSimulator()
+	// Up arrow, F1, shift-F5 and a lone escape key.
+	keys := "\x1b[Aa\x1b[B\x1bOP\x1b[15;2~p\x1b"
+	if err := s.run(bufio.NewReader(strings.NewReader(keys)), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if string(s.input) != "AP" || s.machine.Positions() != "AAC" {
+		t.Errorf("Expected only AP typed at AAC, got %s at %s", string(s.input), s.machine.Positions())
+	}
+
+	s = newSimulator()
+	if err := s.run(bufio.NewReader(strings.NewReader("\x1b[A")), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if s.machine.Positions() != "AAA" || len(s.input) != 0 {
+		t.Errorf("Expected an arrow key to leave the rotors at AAA, got %s", s.machine.Positions())
+	}
+}
+
+func TestSimulatorSettings(t *testing.T) {
+	s := newSimulator()
+	typeKeys(s, "1223455566")
+	if s.machine.Positions() != "BCB" {
+		t.Errorf("Expected BCB, got %s", s.machine.Positions())
+	}
+	if s.machine.Rings() != "BDC" {
+		t.Errorf("Expected BDC, got %s", s.machine.Rings())
+	}
+
+	typeKeys(s, "=ab=qz")
+	if s.machine.Plugboard().String() != "AB QZ" {
+		t.Errorf("Expected AB QZ, got %s", s.machine.Plugboard())
+	}
+	typeKeys(s, "=bc")
+	if s.machine.Plugboard().String() != "QZ" {
+		t.Errorf("Expected QZ, got %s", s.machine.Plugboard())
+	}
+}
+
+func TestSimulatorQuit(t *testing.T) {
+	s := newSimulator()
+	if !s.handleKey('a') {
+		t.Errorf("Expected a key press to continue")
+	}
+	if s.handleKey(keyCtrlC) {
+		t.Errorf("Expected ctrl-c to quit")
+	}
+}
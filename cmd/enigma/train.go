@@ -0,0 +1,90 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/mww/enigma-go/ngram"
+)
+
+// Trains an n-gram model, for crack --model, from text files or stdin.
+func train(args []string, in io.Reader, out io.Writer) error {
+	flags := newFlagSet("train")
+	n := flags.Int("n", 4, "The length of the runs of letters to count.")
+	format := flags.String("format", "binary", "Write the model as binary or text.")
+	outFile := flags.String("out", "", "Write to this file instead of stdout.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *format != "binary" && *format != "text" {
+		return errors.New("--format must be binary or text")
+	}
+
+	t, err := ngram.NewTrainer(*n)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		if _, err := io.Copy(t, in); err != nil {
+			return err
+		}
+	}
+	for _, path := range flags.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(t, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if *outFile == "" {
+		return writeModel(out, t, *format)
+	}
+	// Written to a temporary file and renamed, so a failed run never leaves
+	// an empty or partial model behind.
+	tmp := *outFile + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = writeModel(f, t, *format)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, *outFile)
+}
+
+func writeModel(w io.Writer, t *ngram.Trainer, format string) error {
+	if format == "text" {
+		return t.WriteText(w)
+	}
+	m, err := t.Model()
+	if err != nil {
+		return err
+	}
+	return m.WriteBinary(w)
+}
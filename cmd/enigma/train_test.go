@@ -0,0 +1,73 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrainCommand(t *testing.T) {
+	out := runArgs(t, "The theme, then.", "train", "--n=3", "--format=text")
+	if !strings.HasPrefix(out, "THE 3\n") {
+		t.Errorf("Expected THE to be counted 3 times, got %q", out)
+	}
+
+	dir, err := ioutil.TempDir("", "train")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	corpus := filepath.Join(dir, "corpus.txt")
+	model := filepath.Join(dir, "model.bin")
+	ioutil.WriteFile(corpus, []byte("It was the best of times, it was the worst of times."), 0644)
+	runArgs(t, "", "train", "--n=2", "--out="+model, corpus)
+
+	// Training on text without letters fails and leaves no model behind.
+	empty := filepath.Join(dir, "empty.bin")
+	if err := runCommand([]string{"train", "--out=" + empty}, strings.NewReader("1234"), ioutil.Discard); err == nil {
+		t.Errorf("Expected an error training on no letters")
+	}
+	if _, err := os.Stat(empty); !os.IsNotExist(err) {
+		t.Errorf("Expected no model to be written, got %v", err)
+	}
+	if _, err := os.Stat(empty + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected the temporary file to be removed, got %v", err)
+	}
+
+	// The model can be used to crack with.
+	message := strings.TrimSpace(runArgs(t, "AAAAAAAAAA", "encrypt"))
+	out = runArgs(t, "", "crack", "--message="+message, "--reflectors=B", "--results=1", "--model="+model)
+	if strings.Count(out, "\n") != 2 {
+		t.Errorf("Expected a result, got %q", out)
+	}
+}
+
+func TestTrainErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"train", "--n=9"},
+		{"train", "--format=xml"},
+		{"train", "/does/not/exist"},
+		{"crack", "--message=ABC", "--model=/does/not/exist"},
+	} {
+		if err := runCommand(args, strings.NewReader("some text"), ioutil.Discard); err == nil {
+			t.Errorf("Expected an error from %v", args)
+		}
+	}
+}
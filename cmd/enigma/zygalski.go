@@ -0,0 +1,116 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mww/enigma-go/zygalski"
+)
+
+// Stacks Zygalski sheets for the females among a day's indicators.
+func stackSheets(args []string, in io.Reader, out io.Writer) error {
+	flags := newFlagSet("zygalski")
+	inFile := flags.String("in", "-", "Read the indicators, one ground setting and key a line, from this file, - for stdin.")
+	wheels := flags.String("wheels", "army",
+		"The rotors to choose the wheel order from, or army for I-V or naval for I-VIII.")
+	reflectors := flags.String("reflectors", "B", "The reflectors to try.")
+	sheets := flags.String("sheets", "", "Also draw the sheets of every wheel order as PNG images in this directory.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if set, ok := wheelSets[strings.ToLower(*wheels)]; ok {
+		*wheels = set
+	}
+
+	text, err := readInput(*inFile, in)
+	if err != nil {
+		return err
+	}
+	var indicators []zygalski.Indicator
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		i, err := zygalski.ParseIndicator(line)
+		if err != nil {
+			return err
+		}
+		indicators = append(indicators, i)
+	}
+
+	opts := zygalski.Options{Rotors: strings.Split(*wheels, ","), Reflectors: strings.Split(*reflectors, ",")}
+	keys, err := zygalski.Search(context.Background(), indicators, opts)
+	if err != nil {
+		return err
+	}
+	if *sheets != "" {
+		if err := drawSheets(*sheets, opts); err != nil {
+			return err
+		}
+	}
+	for _, k := range keys {
+		fmt.Fprintf(out, "%s %s %s\n", k.Reflector, strings.Join(k.Rotors, "-"), k.Rings)
+	}
+	_, err = fmt.Fprintf(out, "%d apertures open\n", len(keys))
+	return err
+}
+
+// Writes a PNG of each sheet, named after its wheel order and left position.
+func drawSheets(dir string, opts zygalski.Options) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, reflector := range opts.Reflectors {
+		for _, a := range opts.Rotors {
+			for _, b := range opts.Rotors {
+				for _, c := range opts.Rotors {
+					if a == b || a == c || b == c {
+						continue
+					}
+					s, err := zygalski.NewSheets(reflector, []string{a, b, c})
+					if err != nil {
+						return err
+					}
+					for left := 'A'; left <= 'Z'; left++ {
+						name := fmt.Sprintf("%s-%s-%s-%s-%c.png", reflector, a, b, c, left)
+						if err := writeSheet(filepath.Join(dir, name), s, left); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func writeSheet(path string, s *zygalski.Sheets, left rune) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.WritePNG(f, left); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -30,7 +30,11 @@ type SortedFixedSizeList struct {
 	freeList   chan *node
 }
 
+// A list of size 0 or less is always empty.
 func NewSortedFixedSizeList(size int) *SortedFixedSizeList {
+	if size < 0 {
+		size = 0
+	}
 	l := SortedFixedSizeList{}
 	l.maxSize = size
 	l.freeList = make(chan *node, size)
@@ -61,13 +65,18 @@ func (l *SortedFixedSizeList) MaybeAdd(item Comparer) bool {
 		return true // Because we added the item
 	default:
 		// The list has reached it max size
-		if l.tail.data.Less(item) {
+		if l.tail != nil && l.tail.data.Less(item) {
 			// We should add the item to the list. Remove the current tail to
 			// reuse the node.
 			toAdd = l.tail
 			prev := l.tail.prev
 
 			toAdd.next, toAdd.prev, toAdd.data = nil, nil, item
+			if prev == nil {
+				// The list only holds one item.
+				l.head = toAdd
+				return true
+			}
 
 			prev.next = nil
 			l.tail = prev
@@ -87,8 +96,8 @@ func (l *SortedFixedSizeList) addToList(toAdd *node) {
 		l.head = toAdd
 		toAdd.prev = nil
 		return
-	} else if toAdd.data.Less(l.tail.data) {
-		// Adding to tail.
+	} else if !l.tail.data.Less(toAdd.data) {
+		// Adding to tail, which is also where items equal to the tail go.
 		toAdd.prev = l.tail
 		l.tail.next = toAdd
 		l.tail = toAdd
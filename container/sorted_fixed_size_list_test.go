@@ -71,3 +71,33 @@ func TestAddItemsToListOutOfOrder(t *testing.T) {
 
 	assertValues(t, []int32{25, 13, 8}, l.Iterator())
 }
+
+func TestAddEqualItemsToList(t *testing.T) {
+	l := NewSortedFixedSizeList(3)
+	l.MaybeAdd(&Int{2})
+	l.MaybeAdd(&Int{1})
+	l.MaybeAdd(&Int{1})
+	l.MaybeAdd(&Int{2})
+	l.MaybeAdd(&Int{2})
+
+	assertValues(t, []int32{2, 2, 2}, l.Iterator())
+}
+
+func TestListOfOne(t *testing.T) {
+	l := NewSortedFixedSizeList(1)
+	l.MaybeAdd(&Int{3})
+	l.MaybeAdd(&Int{5})
+	l.MaybeAdd(&Int{4})
+
+	assertValues(t, []int32{5}, l.Iterator())
+}
+
+func TestEmptyList(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		l := NewSortedFixedSizeList(size)
+		if l.MaybeAdd(&Int{3}) {
+			t.Errorf("Expected a list of size %d not to add anything", size)
+		}
+		assertValues(t, []int32{}, l.Iterator())
+	}
+}
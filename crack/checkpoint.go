@@ -0,0 +1,179 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package crack
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+
+	"github.com/mww/enigma-go/container"
+)
+
+// The best results from one unit of work.
+type unitResults struct {
+	unit    int
+	results *container.SortedFixedSizeList
+}
+
+/*
+	Tracks which units of work are done. Units finish out of order, so their
+	results are held back until every unit before them has finished. That way
+	the state can always be described by a single position in the key space
+	and the results of everything before it.
+*/
+type searchState struct {
+	// Every unit before next is done and its results are in best.
+	next    int
+	size    int
+	best    *container.SortedFixedSizeList
+	pending map[int]*container.SortedFixedSizeList
+}
+
+func newSearchState(numberOfResults, first int) *searchState {
+	return &searchState{
+		next:    first,
+		size:    numberOfResults,
+		best:    container.NewSortedFixedSizeList(numberOfResults),
+		pending: make(map[int]*container.SortedFixedSizeList),
+	}
+}
+
+func (s *searchState) add(r unitResults) {
+	s.pending[r.unit] = r.results
+	for {
+		l, ok := s.pending[s.next]
+		if !ok {
+			return
+		}
+		for itr := l.Iterator(); itr.HasNext(); {
+			s.best.MaybeAdd(itr.Next())
+		}
+		delete(s.pending, s.next)
+		s.next++
+	}
+}
+
+// All of the results so far, including units that finished out of order.
+func (s *searchState) results() []Result {
+	all := container.NewSortedFixedSizeList(s.size)
+	for itr := s.best.Iterator(); itr.HasNext(); {
+		all.MaybeAdd(itr.Next())
+	}
+	for _, l := range s.pending {
+		for itr := l.Iterator(); itr.HasNext(); {
+			all.MaybeAdd(itr.Next())
+		}
+	}
+
+	var results []Result
+	for itr := all.Iterator(); itr.HasNext(); {
+		results = append(results, *itr.Next().(*Result))
+	}
+	return results
+}
+
+/*
+	What is written to the checkpoint file: the search, which must match for
+	the checkpoint to be resumed, and how far it has got.
+*/
+type checkpoint struct {
+	Ciphertext string   `json:"ciphertext"`
+	Rotors     []string `json:"rotors"`
+	Reflectors []string `json:"reflectors"`
+	Rings      bool     `json:"rings"`
+	Shard      Shard    `json:"shard"`
+	Scorer     string   `json:"scorer"`
+	Language   string   `json:"language,omitempty"`
+	Model      string   `json:"model,omitempty"` // See ngram.Model.Fingerprint.
+	Steckers   int      `json:"steckers,omitempty"`
+	Candidates int      `json:"candidates,omitempty"`
+	Strategy   string   `json:"strategy,omitempty"`
+
+	Next    int      `json:"next"`
+	Results []Result `json:"results"`
+}
+
+// Describes the search of ciphertext with opts, as it is checkpointed.
+func describeSearch(ciphertext string, opts Options) checkpoint {
+	c := checkpoint{
+		Ciphertext: ciphertext,
+		Rotors:     opts.Rotors,
+		Reflectors: opts.Reflectors,
+		Rings:      opts.Rings,
+		Shard:      opts.Shard,
+		Steckers:   opts.Steckers,
+	}
+	c.Scorer, c.Language, c.Model = describeScorer(opts.Scorer)
+	if opts.Steckers > 0 {
+		c.Candidates = opts.Candidates
+		c.Strategy = opts.Strategy.Name()
+	}
+	return c
+}
+
+/*
+	Writes the state of the search to a temporary file and then renames it,
+	so a crash while saving never leaves a broken checkpoint behind.
+*/
+func (s *searchState) save(path string, search checkpoint) error {
+	c := search
+	c.Next = s.next
+	for itr := s.best.Iterator(); itr.HasNext(); {
+		c.Results = append(c.Results, *itr.Next().(*Result))
+	}
+
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+/*
+	Restores the state from a checkpoint file, if there is one. It is an
+	error if the checkpoint is for a different search.
+*/
+func (s *searchState) load(path string, search checkpoint) error {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var c checkpoint
+	if err := json.Unmarshal(b, &c); err != nil {
+		return err
+	}
+	saved := c
+	saved.Next, saved.Results = 0, nil
+	if !reflect.DeepEqual(saved, search) {
+		return errors.New("the checkpoint " + path + " is for a different search")
+	}
+
+	s.next = c.Next
+	for i := range c.Results {
+		s.best.MaybeAdd(&c.Results[i])
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package crack
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	enigma "github.com/mww/enigma-go"
+	"github.com/mww/enigma-go/container"
+	"github.com/mww/enigma-go/frequency"
+	"github.com/mww/enigma-go/ngram"
+)
+
+func listOf(results ...*Result) *container.SortedFixedSizeList {
+	l := container.NewSortedFixedSizeList(len(results))
+	for _, r := range results {
+		l.MaybeAdd(r)
+	}
+	return l
+}
+
+func TestCheckpointResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "search.json")
+
+	key := enigma.Key{Reflector: "B", Rotors: []string{"III", "I", "II"}, Positions: "QEV"}
+	encrypted := encrypt(t, key, englishText)
+
+	// Stop the first run once a few units are done.
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := Options{
+		Reflectors: []string{"B"},
+		Results:    2,
+		Workers:    1,
+		Checkpoint: path,
+		Progress: func(p Progress) {
+			if p.Tested >= 10*26*26 {
+				cancel()
+			}
+		},
+		ProgressInterval: time.Millisecond,
+	}
+	opts.setDefaults()
+	if _, err := Run(ctx, encrypted, opts); err != context.Canceled {
+		t.Fatalf("Expected %s, got %v", context.Canceled, err)
+	}
+
+	s := newSearchState(2, 0)
+	if err := s.load(path, describeSearch(encrypted, opts)); err != nil {
+		t.Fatalf("Unexpected error loading the checkpoint: %s", err)
+	}
+	if s.next == 0 || s.next >= 6*26 {
+		t.Fatalf("Expected the checkpoint to be part way through, got %d", s.next)
+	}
+
+	var last Progress
+	opts.Progress = func(p Progress) { last = p }
+	results, err := Run(context.Background(), encrypted, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if results[0].Key.String() != key.String() {
+		t.Errorf("Expected %s, got %s", key, results[0].Key)
+	}
+	if last.Tested != last.Total {
+		t.Errorf("Expected the final progress to be complete, got %s", last)
+	}
+
+	s = newSearchState(2, 0)
+	if err := s.load(path, describeSearch(encrypted, opts)); err != nil {
+		t.Fatalf("Unexpected error loading the checkpoint: %s", err)
+	}
+	if s.next != 6*26 {
+		t.Errorf("Expected the checkpoint to be complete, got %d", s.next)
+	}
+}
+
+func TestCheckpointForDifferentSearch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "search.json")
+
+	opts := Options{Checkpoint: path}
+	opts.setDefaults()
+	if err := newSearchState(3, 0).save(path, describeSearch("ABC", opts)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := Run(context.Background(), "XYZ", opts); err == nil {
+		t.Errorf("Expected an error resuming a different message")
+	}
+
+	german, _ := frequency.LanguageByName("german")
+	chi2, _ := ScorerByName("chi2")
+	unigram, _ := ScorerForLanguage("unigram", german)
+	model, _ := ngram.English(3)
+	for name, change := range map[string]func(o *Options){
+		"scorer":     func(o *Options) { o.Scorer = chi2 },
+		"language":   func(o *Options) { o.Scorer = unigram },
+		"model":      func(o *Options) { o.Scorer = model },
+		"steckers":   func(o *Options) { o.Steckers = 5 },
+		"candidates": func(o *Options) { o.Steckers, o.Candidates = 5, 10 },
+		"strategy":   func(o *Options) { o.Steckers, o.Strategy = 5, RandomRestart(0, 0) },
+	} {
+		other := opts
+		change(&other)
+		if _, err := Run(context.Background(), "ABC", other); err == nil {
+			t.Errorf("Expected an error resuming with a different %s", name)
+		}
+	}
+}
+
+func TestSearchStateOutOfOrder(t *testing.T) {
+	s := newSearchState(3, 0)
+	for _, u := range []int{2, 1} {
+		s.add(unitResults{u, listOf(&Result{Score: float64(u)})})
+	}
+	if s.next != 0 {
+		t.Errorf("Expected to wait for unit 0, got %d", s.next)
+	}
+	if len(s.results()) != 2 {
+		t.Errorf("Expected the pending results, got %v", s.results())
+	}
+	s.add(unitResults{0, listOf(&Result{Score: 0})})
+	if s.next != 3 || len(s.pending) != 0 {
+		t.Errorf("Expected every unit to be done, got %d with %d pending", s.next, len(s.pending))
+	}
+}
@@ -0,0 +1,308 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+/*
+	Package crack searches for the key of an Enigma message.
+*/
+package crack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	enigma "github.com/mww/enigma-go"
+	"github.com/mww/enigma-go/container"
+)
+
+// A candidate key and the plaintext it decrypts the message to.
+type Result struct {
+	Key       enigma.Key `json:"key"`
+	Plaintext string     `json:"plaintext"`
+	Score     float64    `json:"score"`
+}
+
+func (r *Result) Less(other container.Comparer) bool {
+	o, ok := other.(*Result)
+	if !ok {
+		return false
+	}
+	// The higher the score the better.
+	return r.Score < o.Score
+}
+
+// How far a search has got.
+type Progress struct {
+	Tested, Total int64         // Configurations, including any resumed from a checkpoint.
+	Elapsed       time.Duration // Since this run started.
+	Remaining     time.Duration // Estimated from the rate of this run.
+}
+
+type Options struct {
+	// The rotors to choose from, by name. Defaults to I, II and III.
+	Rotors []string
+
+	// The reflectors to try, by name. Defaults to A, B and C.
+	Reflectors []string
+
+	// Also try every ring setting of the middle and right rotors.
+	Rings bool
+
+	/*
+		The most plug pairs to look for, 0 to assume there is no plugboard.
+		Plugboards are found by hill climbing the best Candidates (default
+		100) rotor settings, ranked by index of coincidence.
+	*/
+	Steckers   int
+	Candidates int
+
+	// How to look for plug pairs. Defaults to HillClimb.
+	Strategy Strategy
+
+	// Defaults to UnigramScore, or BigramScore when looking for steckers.
+	Scorer Scorer
+
+	// The number of results to keep. Defaults to 3.
+	Results int
+
+	// The number of configurations to try at once. Defaults to GOMAXPROCS.
+	Workers int
+
+	// If set, called every ProgressInterval (default 1s) and once at the end.
+	Progress         func(Progress)
+	ProgressInterval time.Duration
+
+	/*
+		If set, the state of the search is saved to this file every
+		CheckpointInterval (default 1 minute) and when the search stops. If
+		the file already exists the search resumes from it.
+	*/
+	Checkpoint         string
+	CheckpointInterval time.Duration
+
+	// Limits the search to part of the key space, see Split.
+	Shard Shard
+}
+
+func (o *Options) setDefaults() {
+	if len(o.Rotors) == 0 {
+		o.Rotors = []string{"I", "II", "III"}
+	}
+	if len(o.Reflectors) == 0 {
+		o.Reflectors = []string{"A", "B", "C"}
+	}
+	if o.Scorer == nil && o.Steckers > 0 {
+		o.Scorer, _ = ScorerByName("bigram")
+	} else if o.Scorer == nil {
+		o.Scorer, _ = ScorerByName("unigram")
+	}
+	if o.Results < 1 {
+		o.Results = 3
+	}
+	if o.Workers < 1 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+	if o.ProgressInterval <= 0 {
+		o.ProgressInterval = time.Second
+	}
+	if o.CheckpointInterval <= 0 {
+		o.CheckpointInterval = time.Minute
+	}
+	if o.Candidates <= 0 {
+		o.Candidates = 100
+	}
+	if o.Strategy.search == nil {
+		o.Strategy = HillClimb()
+	}
+}
+
+/*
+	Run tries every wheel order of the chosen rotors with each reflector and
+	every starting position, and returns the best results, best first.
+
+	If ctx is cancelled the search stops and the best results found so far
+	are returned along with ctx.Err().
+*/
+func Run(ctx context.Context, ciphertext string, opts Options) ([]Result, error) {
+	opts.setDefaults()
+	ciphertext = strings.ToUpper(ciphertext)
+	if ciphertext == "" || strings.IndexFunc(ciphertext, notLetter) >= 0 {
+		return nil, errors.New("the message must only contain the letters A-Z")
+	}
+	if opts.Steckers > 13 {
+		return nil, fmt.Errorf("a plugboard has at most 13 pairs, not %d", opts.Steckers)
+	} else if opts.Steckers > 0 {
+		return runWithSteckers(ctx, ciphertext, opts)
+	}
+	return run(ctx, ciphertext, opts, describeSearch(ciphertext, opts))
+}
+
+/*
+	Searches the rotor settings with opts.Scorer. The checkpoint records the
+	search as search, which differs from opts when Run is looking for
+	plugboards.
+*/
+func run(ctx context.Context, ciphertext string, opts Options, search checkpoint) ([]Result, error) {
+	space, err := newKeyspace(opts.Rotors, opts.Reflectors, opts.Rings, len(ciphertext))
+	if err != nil {
+		return nil, err
+	}
+
+	first, last, err := opts.Shard.bounds(space.size())
+	if err != nil {
+		return nil, err
+	}
+
+	s := newSearchState(opts.Results, first)
+	if opts.Checkpoint != "" {
+		if err := s.load(opts.Checkpoint, search); err != nil {
+			return nil, err
+		}
+	}
+
+	units := make(chan int, opts.Workers)
+	go func() {
+		defer close(units)
+		for i := s.next; i < last; i++ {
+			select {
+			case units <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	tested := space.configurations(first, s.next)
+	stopProgress := reportProgress(opts, space.configurations(first, last), &tested)
+
+	// Each worker sends back the best results of every unit it finishes.
+	finished := make(chan unitResults, opts.Workers)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range units {
+				if ctx.Err() != nil {
+					continue
+				}
+				l := container.NewSortedFixedSizeList(opts.Results)
+				space.run(u, ciphertext, opts.Scorer, l)
+				atomic.AddInt64(&tested, space.configurations(u, u+1))
+				finished <- unitResults{u, l}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	var saveErr error
+	var checkpoints <-chan time.Time
+	if opts.Checkpoint != "" {
+		ticker := time.NewTicker(opts.CheckpointInterval)
+		defer ticker.Stop()
+		checkpoints = ticker.C
+	}
+	for done := false; !done; {
+		select {
+		case r, ok := <-finished:
+			if ok {
+				s.add(r)
+			} else {
+				done = true
+			}
+		case <-checkpoints:
+			// Failing to save is reported once the search stops.
+			if saveErr == nil {
+				saveErr = s.save(opts.Checkpoint, search)
+			}
+		}
+	}
+	stopProgress()
+
+	if opts.Checkpoint != "" && saveErr == nil {
+		saveErr = s.save(opts.Checkpoint, search)
+	}
+	if saveErr != nil {
+		return nil, saveErr
+	}
+	return s.results(), ctx.Err()
+}
+
+/*
+	Calls opts.Progress every interval until the returned function is called,
+	which also makes one final call. The configurations already tested when
+	it is called, e.g. by a run resumed from a checkpoint, don't count
+	towards the rate the time remaining is estimated from.
+*/
+func reportProgress(opts Options, total int64, tested *int64) func() {
+	if opts.Progress == nil {
+		return func() {}
+	}
+	start, resumed := time.Now(), atomic.LoadInt64(tested)
+	report := func() {
+		p := Progress{
+			Tested:  atomic.LoadInt64(tested),
+			Total:   total,
+			Elapsed: time.Since(start),
+		}
+		if n := p.Tested - resumed; n > 0 {
+			p.Remaining = time.Duration(float64(p.Elapsed) * float64(p.Total-p.Tested) / float64(n))
+		}
+		opts.Progress(p)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(opts.ProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				report()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+		report()
+	}
+}
+
+func notLetter(r rune) bool {
+	return r < 'A' || r > 'Z'
+}
+
+func (p Progress) String() string {
+	percent := 0.0
+	if p.Total > 0 {
+		percent = 100 * float64(p.Tested) / float64(p.Total)
+	}
+	return fmt.Sprintf("%d/%d (%.1f%%) tested, %s elapsed, about %s remaining",
+		p.Tested, p.Total, percent, p.Elapsed.Round(time.Second), p.Remaining.Round(time.Second))
+}
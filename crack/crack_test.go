@@ -0,0 +1,217 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package crack
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	enigma "github.com/mww/enigma-go"
+	"github.com/mww/enigma-go/container"
+)
+
+const englishText = "ITWASTHEBESTOFTIMESITWASTHEWORSTOFTIMESITWASTHEAGEOFWISDOM" +
+	"ITWASTHEAGEOFFOOLISHNESSITWASTHEEPOCHOFBELIEFITWASTHEEPOCHOFINCREDULITY" +
+	"ITWASTHESEASONOFLIGHTITWASTHESEASONOFDARKNESS"
+
+func encrypt(t testing.TB, k enigma.Key, text string) string {
+	m, err := k.NewMachine()
+	if err != nil {
+		t.Fatalf("Invalid key %s: %s", k, err)
+	}
+	return m.Encrypt(text)
+}
+
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping full test in short mode.")
+	}
+
+	encrypted := "ZTQBLVXKPBPGAVQBRYDYQEZNKRLMZTMRGBJSQKHDPHHNTNIDLYVFCOKZYYSMJFAHQBTEAVFKOXRPSQX"
+	expected := "THISISASLIGHTLYLONGERTESTSOIHAVETOSEEIFICANKEEPWRITINGALONGERSTRINGTOUSEASINPUT"
+	results, err := Run(context.Background(), encrypted, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if results[0].Plaintext != expected {
+		t.Errorf("Expected %s, got %s", expected, results[0].Plaintext)
+	}
+}
+
+func TestRunFindsKey(t *testing.T) {
+	key := enigma.Key{Reflector: "B", Rotors: []string{"III", "I", "II"}, Positions: "QEV"}
+	encrypted := encrypt(t, key, englishText)
+
+	var last Progress
+	results, err := Run(context.Background(), encrypted, Options{
+		Reflectors: []string{"B"},
+		Results:    2,
+		Progress:   func(p Progress) { last = p },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].Key.String() != key.String() || results[0].Plaintext != englishText {
+		t.Errorf("Expected %s, got %s %s", key, results[0].Key, results[0].Plaintext)
+	}
+	if results[0].Score < results[1].Score {
+		t.Errorf("Expected the best result first")
+	}
+	if last.Tested != 6*26*26*26 || last.Total != last.Tested {
+		t.Errorf("Expected the final progress to be complete, got %s", last)
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var last Progress
+	results, err := Run(ctx, "BDZGOBDZGO", Options{
+		Workers:          2,
+		ProgressInterval: 10 * time.Millisecond,
+		Progress: func(p Progress) {
+			if p.Tested > 0 {
+				cancel()
+			}
+			last = p
+		},
+	})
+	if err != context.Canceled {
+		t.Errorf("Expected %s, got %v", context.Canceled, err)
+	}
+	if len(results) != 3 {
+		t.Errorf("Expected the best results so far, got %v", results)
+	}
+	if last.Tested >= last.Total {
+		t.Errorf("Expected the search to stop early, got %s", last)
+	}
+}
+
+func TestProgressAfterResume(t *testing.T) {
+	// Half the search was done before this run started.
+	tested := int64(500)
+	var last Progress
+	stop := reportProgress(Options{
+		ProgressInterval: time.Hour,
+		Progress:         func(p Progress) { last = p },
+	}, 1000, &tested)
+	time.Sleep(10 * time.Millisecond)
+	atomic.AddInt64(&tested, 100)
+	stop()
+
+	// This run tested 100 in Elapsed, so 400 more take 4 times as long.
+	expected := 4 * last.Elapsed
+	if last.Remaining < expected-time.Microsecond || last.Remaining > expected+time.Microsecond {
+		t.Errorf("Expected %s remaining after %s, got %s", expected, last.Elapsed, last.Remaining)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	if _, err := Run(context.Background(), "NOT LETTERS", Options{}); err == nil {
+		t.Errorf("Expected an error for a message with spaces")
+	}
+	if _, err := Run(context.Background(), "ABC", Options{Rotors: []string{"I", "II"}}); err == nil {
+		t.Errorf("Expected an error for too few rotors")
+	}
+	if _, err := Run(context.Background(), "ABC", Options{Reflectors: []string{"Z"}}); err == nil {
+		t.Errorf("Expected an error for an unknown reflector")
+	}
+	if _, err := Run(context.Background(), "ABC", Options{Steckers: 14}); err == nil {
+		t.Errorf("Expected an error for too many plug pairs")
+	}
+}
+
+var benchmarkMessage = "ZTQBLVXKPBPGAVQBRYDYQEZNKRLMZTMRGBJSQKHDPHHNTNIDLYVFCOKZYYSMJFAHQBTEAVFKOXRPSQX"
+
+/*
+	Runs f while sampling memory, and reports the peak stack and heap in use
+	alongside the usual allocation counts, since goroutine stacks don't show
+	up in those.
+*/
+func reportPeakMemory(b *testing.B, f func()) {
+	done := make(chan struct{})
+	peak := make(chan uint64)
+	go func() {
+		var max uint64
+		var stats runtime.MemStats
+		for {
+			runtime.ReadMemStats(&stats)
+			if inUse := stats.StackInuse + stats.HeapInuse; inUse > max {
+				max = inUse
+			}
+			select {
+			case <-done:
+				peak <- max
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+
+	b.ReportAllocs()
+	f()
+	close(done)
+	b.ReportMetric(float64(<-peak)/(1<<20), "peak-MB")
+}
+
+func BenchmarkRun(b *testing.B) {
+	reportPeakMemory(b, func() {
+		for i := 0; i < b.N; i++ {
+			Run(context.Background(), benchmarkMessage, Options{})
+		}
+	})
+}
+
+/*
+	The search as it was before the worker pool, with a goroutine for each
+	configuration, kept to compare against.
+*/
+func BenchmarkRunGoroutinePerConfiguration(b *testing.B) {
+	space, _ := newKeyspace([]string{"I", "II", "III"}, []string{"A", "B", "C"}, false, 0)
+	reportPeakMemory(b, func() {
+		for i := 0; i < b.N; i++ {
+			writer := make(chan *Result, 250000)
+			count := 0
+			for u := 0; u < space.size(); u++ {
+				o, reflector, left := space.unit(u)
+				order := space.orders[o]
+				for _, middle := range enigma.LETTERS {
+					for _, right := range enigma.LETTERS {
+						m := enigma.NewMachine(space.rotors[order[0]], space.rotors[order[1]],
+							space.rotors[order[2]], space.reflectors[reflector],
+							enigma.LETTERS[left], middle, right)
+						go func() {
+							plaintext := m.Decrypt(benchmarkMessage)
+							writer <- &Result{Plaintext: plaintext, Score: UnigramScore(plaintext)}
+							enigma.FreeMachine(m)
+						}()
+						count++
+					}
+				}
+			}
+
+			resultList := container.NewSortedFixedSizeList(3)
+			for ; count > 0; count-- {
+				resultList.MaybeAdd(<-writer)
+			}
+		}
+	})
+}
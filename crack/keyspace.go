@@ -0,0 +1,199 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package crack
+
+import (
+	"fmt"
+
+	enigma "github.com/mww/enigma-go"
+	"github.com/mww/enigma-go/container"
+)
+
+// The windows of the middle and right rotors at the start of a message.
+type window struct {
+	middle, right rune
+}
+
+/*
+	The configurations to search, split into numbered units of work so they
+	can be handed out to workers. Each unit is a wheel order, reflector and
+	left rotor position.
+*/
+type keyspace struct {
+	rotorNames, reflectorNames []string
+	rotors, reflectors         []*enigma.Rotor
+	orders                     []wheelOrder
+
+	// The windows to try for each wheel order, and whether to try every
+	// ring setting of the middle and right rotors for each of them.
+	windows [][]window
+	rings   bool
+}
+
+// A wheel order, by index into keyspace.rotors.
+type wheelOrder [3]int
+
+/*
+	Sets up the search for a message of the given length. Without rings
+	every window of the middle and right rotors is tried with the rings at
+	A, which is all of the distinct settings.
+*/
+func newKeyspace(rotorNames, reflectorNames []string, rings bool, length int) (*keyspace, error) {
+	k := keyspace{rotorNames: rotorNames, reflectorNames: reflectorNames, rings: rings}
+	for _, name := range rotorNames {
+		r, err := enigma.RotorByName(name)
+		if err != nil {
+			return nil, err
+		}
+		k.rotors = append(k.rotors, r)
+	}
+	for _, name := range reflectorNames {
+		r, err := enigma.ReflectorByName(name)
+		if err != nil {
+			return nil, err
+		}
+		k.reflectors = append(k.reflectors, r)
+	}
+
+	for a := range k.rotors {
+		for b := range k.rotors {
+			for c := range k.rotors {
+				if a != b && a != c && b != c {
+					k.orders = append(k.orders, wheelOrder{a, b, c})
+				}
+			}
+		}
+	}
+	if len(k.orders) == 0 {
+		return nil, fmt.Errorf("need at least 3 rotors, got %d", len(rotorNames))
+	}
+
+	for _, o := range k.orders {
+		if rings {
+			k.windows = append(k.windows, k.distinctWindows(o, length))
+		} else {
+			k.windows = append(k.windows, allWindows)
+		}
+	}
+	return &k, nil
+}
+
+var allWindows = func() []window {
+	var w []window
+	for _, middle := range enigma.LETTERS {
+		for _, right := range enigma.LETTERS {
+			w = append(w, window{middle, right})
+		}
+	}
+	return w
+}()
+
+/*
+	Each rotor's ring and window only matter together as an offset, apart
+	from when the window reaches a notch. So once every ring setting is
+	being tried, two windows that step the same way for the whole message
+	give the same results, and only the first of them needs trying. For a
+	short message most windows of the right rotor never step the middle one.
+	The left rotor's ring is never needed, it is the same as a different
+	left position.
+*/
+func (k *keyspace) distinctWindows(o wheelOrder, length int) []window {
+	m := enigma.NewMachine(k.rotors[o[0]], k.rotors[o[1]], k.rotors[o[2]], k.reflectors[0], 'A', 'A', 'A')
+	defer enigma.FreeMachine(m)
+
+	var windows []window
+	seen := make(map[string]bool)
+	for _, w := range allWindows {
+		m.SetPositions('A', w.middle, w.right)
+		// How far the left and middle rotors have moved after each letter.
+		steps := make([]byte, 0, 2*length)
+		for i := 0; i < length; i++ {
+			m.Step('A')
+			p := m.Positions()
+			steps = append(steps, p[0], byte((rune(p[1])-w.middle+26)%26))
+		}
+		if !seen[string(steps)] {
+			seen[string(steps)] = true
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
+
+// The number of units of work.
+func (k *keyspace) size() int {
+	return len(k.orders) * len(k.reflectors) * 26
+}
+
+// Splits a unit of work into its wheel order, reflector and left position.
+func (k *keyspace) unit(i int) (order int, reflector int, left int32) {
+	left = int32(i % 26)
+	i /= 26
+	reflector = i % len(k.reflectors)
+	order = i / len(k.reflectors)
+	return
+}
+
+// The number of configurations in the units from first up to last.
+func (k *keyspace) configurations(first, last int) int64 {
+	var n int64
+	for u := first; u < last; u++ {
+		order, _, _ := k.unit(u)
+		n += int64(len(k.windows[order]))
+	}
+	if k.rings {
+		n *= 26 * 26
+	}
+	return n
+}
+
+// Tries every configuration in the unit of work, adding them to results.
+func (k *keyspace) run(u int, ciphertext string, score Scorer, results *container.SortedFixedSizeList) {
+	order, reflector, left := k.unit(u)
+	o := k.orders[order]
+	m := enigma.NewMachine(k.rotors[o[0]], k.rotors[o[1]], k.rotors[o[2]],
+		k.reflectors[reflector], 'A', 'A', 'A')
+	defer enigma.FreeMachine(m)
+
+	rings := []rune{'A'}
+	if k.rings {
+		rings = enigma.LETTERS
+	}
+	names := []string{k.rotorNames[o[0]], k.rotorNames[o[1]], k.rotorNames[o[2]]}
+	l := enigma.LETTERS[left]
+	for _, w := range k.windows[order] {
+		for _, g2 := range rings {
+			for _, g3 := range rings {
+				m.SetRings('A', g2, g3)
+				m.SetPositions(l, w.middle, w.right)
+				plaintext := m.Decrypt(ciphertext)
+				r := &Result{
+					Key: enigma.Key{
+						Reflector: k.reflectorNames[reflector],
+						Rotors:    names,
+						Positions: string([]rune{l, w.middle, w.right}),
+					},
+					Plaintext: plaintext,
+					Score:     score.Score(plaintext),
+				}
+				if k.rings {
+					r.Key.Rings = string([]rune{'A', g2, g3})
+				}
+				results.MaybeAdd(r)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package crack
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+func TestDistinctWindows(t *testing.T) {
+	space, err := newKeyspace([]string{"I", "II", "III"}, []string{"B"}, true, 20)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	windows := space.windows[0]
+	if len(windows) >= len(allWindows) {
+		t.Fatalf("Expected fewer than %d windows, got %d", len(allWindows), len(windows))
+	}
+
+	// Every key should decrypt the same as one of the windows with some rings.
+	const message = "ABCDEFGHIJKLMNOPQRST"
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		k := enigma.Key{
+			Reflector: "B",
+			Rotors:    []string{"I", "II", "III"},
+			Rings:     "A" + randomLetters(r, 2),
+			Positions: randomLetters(r, 3),
+		}
+		m, _ := k.NewMachine()
+		expected := m.Decrypt(message)
+
+		if !matchesWindow(m, windows, rune(k.Positions[0]), message, expected) {
+			t.Errorf("Expected %s to match one of the windows", k)
+		}
+	}
+}
+
+func matchesWindow(m *enigma.Machine, windows []window, left rune, message, expected string) bool {
+	for _, w := range windows {
+		for _, g2 := range enigma.LETTERS {
+			for _, g3 := range enigma.LETTERS {
+				m.SetRings('A', g2, g3)
+				m.SetPositions(left, w.middle, w.right)
+				if m.Decrypt(message) == expected {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func randomLetters(r *rand.Rand, n int) string {
+	s := make([]rune, n)
+	for i := range s {
+		s[i] = enigma.LETTERS[r.Intn(26)]
+	}
+	return string(s)
+}
+
+func TestRunRings(t *testing.T) {
+	key := enigma.Key{Reflector: "B", Rotors: []string{"IV", "II", "V"}, Rings: "CKS", Positions: "FZQ"}
+	plaintext := englishText[:60]
+	encrypted := encrypt(t, key, plaintext)
+
+	// Only search the unit with the key, the left ring moves the left position.
+	// Scoring by the letters in the right place checks the search, not the scorer.
+	opts := Options{
+		Rotors:     []string{"II", "IV", "V"},
+		Reflectors: []string{"B"},
+		Rings:      true,
+		Results:    1,
+		Scorer: ScorerFunc(func(s string) float64 {
+			n := 0
+			for i := range s {
+				if s[i] == plaintext[i] {
+					n++
+				}
+			}
+			return float64(n)
+		}),
+	}
+	space, _ := newKeyspace(opts.Rotors, opts.Reflectors, true, len(encrypted))
+	for u := 0; u < space.size(); u++ {
+		o, _, left := space.unit(u)
+		order := space.orders[o]
+		if space.rotorNames[order[0]] == "IV" && space.rotorNames[order[1]] == "II" &&
+			space.rotorNames[order[2]] == "V" && left == ('F'-'C') {
+			opts.Shard = Shard{u, u + 1}
+		}
+	}
+
+	results, err := Run(context.Background(), encrypted, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if results[0].Plaintext != plaintext {
+		t.Errorf("Expected %s, got %s %s", plaintext, results[0].Key, results[0].Plaintext)
+	}
+}
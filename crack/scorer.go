@@ -0,0 +1,223 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package crack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mww/enigma-go/frequency"
+	"github.com/mww/enigma-go/ngram"
+)
+
+/*
+	A Scorer rates how much a candidate plaintext looks like real language.
+	Higher scores are better. Scores are only compared between plaintexts
+	of the same length.
+*/
+type Scorer interface {
+	Score(plaintext string) float64
+}
+
+// ScorerFunc lets an ordinary function be used as a Scorer.
+type ScorerFunc func(plaintext string) float64
+
+func (f ScorerFunc) Score(plaintext string) float64 {
+	return f(plaintext)
+}
+
+/*
+	UnigramScore compares single letter frequencies with English, the score
+	is the negated frequency.Analysis.Diff().
+*/
+func UnigramScore(plaintext string) float64 {
+	return -frequency.English().Diff(plaintext)
+}
+
+/*
+	ChiSquaredScore is the negated chi-squared statistic of the letter counts
+	against English, which weighs differences in rare letters more heavily
+	than UnigramScore does.
+*/
+func ChiSquaredScore(plaintext string) float64 {
+	return ChiSquaredScorer(frequency.English()).Score(plaintext)
+}
+
+// UnigramScorer is UnigramScore for another language.
+func UnigramScorer(l *frequency.Language) Scorer {
+	return ScorerFunc(func(plaintext string) float64 {
+		return -l.Diff(plaintext)
+	})
+}
+
+// ChiSquaredScorer is ChiSquaredScore for another language.
+func ChiSquaredScorer(l *frequency.Language) Scorer {
+	return ScorerFunc(func(plaintext string) float64 {
+		return -l.ChiSquared(plaintext)
+	})
+}
+
+/*
+	IndexOfCoincidence is the chance that two letters picked from the text
+	are the same. It is about 0.067 for English and 0.038 for random
+	letters, and doesn't care which letters are which, so it rewards a
+	plugboard that is partly right long before the text is readable.
+*/
+func IndexOfCoincidence(plaintext string) float64 {
+	if len(plaintext) < 2 {
+		return 0
+	}
+	var counts [26]int
+	for i := 0; i < len(plaintext); i++ {
+		counts[plaintext[i]-'A']++
+	}
+	sum := 0
+	for _, n := range counts {
+		sum += n * (n - 1)
+	}
+	return float64(sum) / float64(len(plaintext)*(len(plaintext)-1))
+}
+
+/*
+	BigramScore is the average log probability of each pair of letters
+	being next to each other in English. It tells apart texts that have the
+	right letters in the wrong places, which UnigramScore can't.
+*/
+func BigramScore(plaintext string) float64 {
+	m, _ := ngram.English(2)
+	return m.Score(plaintext)
+}
+
+var (
+	wordsOnce sync.Once
+	words     map[string]bool
+	longest   int
+)
+
+/*
+	WordScore is the fraction of the plaintext covered by common English
+	words, found by taking the longest word at each letter and carrying on
+	after it. It is only useful once most of the key is right, but then it
+	is the closest to a person reading the text.
+*/
+func WordScore(plaintext string) float64 {
+	wordsOnce.Do(func() {
+		words = make(map[string]bool)
+		for _, w := range frequency.EnglishWords() {
+			words[w] = true
+			if len(w) > longest {
+				longest = len(w)
+			}
+		}
+	})
+	if len(plaintext) == 0 {
+		return 0
+	}
+
+	covered := 0
+	for i := 0; i < len(plaintext); {
+		n := longest
+		if n > len(plaintext)-i {
+			n = len(plaintext) - i
+		}
+		for n > 0 && !words[plaintext[i:i+n]] {
+			n--
+		}
+		if n == 0 {
+			i++
+			continue
+		}
+		covered += n
+		i += n
+	}
+	return float64(covered) / float64(len(plaintext))
+}
+
+var scorers = map[string]func(l *frequency.Language) (Scorer, error){
+	"unigram":  func(l *frequency.Language) (Scorer, error) { return UnigramScorer(l), nil },
+	"chi2":     func(l *frequency.Language) (Scorer, error) { return ChiSquaredScorer(l), nil },
+	"ic":       func(l *frequency.Language) (Scorer, error) { return ScorerFunc(IndexOfCoincidence), nil },
+	"bigram":   englishOnly("bigram", func() (Scorer, error) { return ngram.English(2) }),
+	"trigram":  englishOnly("trigram", func() (Scorer, error) { return ngram.English(3) }),
+	"quadgram": englishOnly("quadgram", func() (Scorer, error) { return ngram.English(4) }),
+	"words":    englishOnly("words", func() (Scorer, error) { return ScorerFunc(WordScore), nil }),
+}
+
+// For the scorers built from English text, which other languages don't have.
+func englishOnly(name string, f func() (Scorer, error)) func(l *frequency.Language) (Scorer, error) {
+	return func(l *frequency.Language) (Scorer, error) {
+		if l != frequency.English() {
+			return nil, fmt.Errorf("the %s scorer is only for english, train a model of %s to use instead", name, l.Name)
+		}
+		return f()
+	}
+}
+
+// ScorerNames returns the names ScorerByName accepts.
+func ScorerNames() []string {
+	var names []string
+	for name := range scorers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ScorerByName returns one of the scorers named by ScorerNames, for English.
+func ScorerByName(name string) (Scorer, error) {
+	return ScorerForLanguage(name, frequency.English())
+}
+
+/*
+	ScorerForLanguage returns one of the scorers named by ScorerNames, for
+	the language. Only the scorers that count single letters can be used
+	with languages other than English.
+*/
+func ScorerForLanguage(name string, l *frequency.Language) (Scorer, error) {
+	name = strings.ToLower(name)
+	f, ok := scorers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scorer %q, expected one of %s", name, strings.Join(ScorerNames(), ", "))
+	}
+	s, err := f(l)
+	if err != nil {
+		return nil, err
+	}
+	return namedScorer{s, name, l.Name}, nil
+}
+
+// A scorer from ScorerForLanguage, which checkpoints record by name.
+type namedScorer struct {
+	Scorer
+	name, language string
+}
+
+/*
+	Describes a scorer for a checkpoint: its name and language, or the
+	fingerprint of an n-gram model. Other scorers can only be told apart by
+	their type.
+*/
+func describeScorer(s Scorer) (name, language, model string) {
+	switch s := s.(type) {
+	case namedScorer:
+		return s.name, s.language, ""
+	case *ngram.Model:
+		return "model", "", s.Fingerprint()
+	}
+	return fmt.Sprintf("%T", s), "", ""
+}
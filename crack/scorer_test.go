@@ -0,0 +1,92 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package crack
+
+import (
+	"testing"
+
+	"github.com/mww/enigma-go/frequency"
+)
+
+func TestIndexOfCoincidence(t *testing.T) {
+	if ic := IndexOfCoincidence("AAAA"); ic != 1 {
+		t.Errorf("Expected 1, got %f", ic)
+	}
+	if ic := IndexOfCoincidence("ABCD"); ic != 0 {
+		t.Errorf("Expected 0, got %f", ic)
+	}
+	if ic := IndexOfCoincidence(longEnglishText); ic < 0.06 {
+		t.Errorf("Expected English to be above 0.06, got %f", ic)
+	}
+}
+
+func TestBigramScore(t *testing.T) {
+	english := BigramScore("THEQUICKBROWNFOXJUMPSOVERTHELAZYDOG")
+	shuffled := BigramScore("QTHEUICBKRWONOFXJMUPSVOETRHLEAZYODG")
+	if english <= shuffled {
+		t.Errorf("Expected English to score higher, got %f and %f", english, shuffled)
+	}
+}
+
+func TestChiSquaredScore(t *testing.T) {
+	english := ChiSquaredScore(longEnglishText)
+	random := ChiSquaredScore("QZXJKVQZXJKVBPWQZXJKVBPWQZXJKVBPWQZXJKVBPWQZXJKVBPW")
+	if english <= random {
+		t.Errorf("Expected English to score higher, got %f and %f", english, random)
+	}
+}
+
+func TestWordScore(t *testing.T) {
+	if s := WordScore("THELIGHTANDTHECOLOURS"); s != 1 {
+		t.Errorf("Expected every letter to be covered, got %f", s)
+	}
+	if s := WordScore("QQQQTHEQQQQ"); s != 3.0/11 {
+		t.Errorf("Expected 3/11 of the letters to be covered, got %f", s)
+	}
+	if s := WordScore(""); s != 0 {
+		t.Errorf("Expected 0 for no text, got %f", s)
+	}
+}
+
+func TestScorerByName(t *testing.T) {
+	for _, name := range ScorerNames() {
+		s, err := ScorerByName(name)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", name, err)
+		}
+		if s.Score(longEnglishText) <= s.Score("QTHEUICBKRWONOFXJMUPSVOETRHLEAZYODGZZZQQQXXXJJJ") {
+			t.Errorf("Expected %s to score English higher", name)
+		}
+	}
+	if _, err := ScorerByName("vibes"); err == nil {
+		t.Errorf("Expected an error for an unknown scorer")
+	}
+}
+
+func TestScorerForLanguage(t *testing.T) {
+	german, _ := frequency.LanguageByName("german")
+	s, err := ScorerForLanguage("unigram", german)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	text := "DIEVERBINDUNGZURDRITTENARMEEISTUNTERBROCHEN"
+	if s.Score(text) <= UnigramScore(text) {
+		t.Errorf("Expected German text to score higher as German")
+	}
+	if _, err := ScorerForLanguage("quadgram", german); err == nil {
+		t.Errorf("Expected an error for a German quadgram scorer")
+	}
+}
@@ -0,0 +1,244 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package crack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/mww/enigma-go/container"
+	"github.com/mww/enigma-go/frequency"
+	"github.com/mww/enigma-go/ngram"
+)
+
+/*
+	A range of units of work, from First up to but not including Last. The
+	zero value is the whole key space.
+*/
+type Shard struct {
+	First int `json:"first"`
+	Last  int `json:"last"`
+}
+
+func (s Shard) bounds(size int) (first, last int, err error) {
+	if s == (Shard{}) {
+		return 0, size, nil
+	}
+	if s.First < 0 || s.First >= s.Last || s.Last > size {
+		return 0, 0, fmt.Errorf("shard %d-%d is outside the key space of %d units", s.First, s.Last, size)
+	}
+	return s.First, s.Last, nil
+}
+
+/*
+	Everything a worker needs to search one shard, so the search can be
+	spread over other processes or machines by passing these around as
+	JSON.
+*/
+type Job struct {
+	Ciphertext string   `json:"ciphertext"`
+	Rotors     []string `json:"rotors"`
+	Reflectors []string `json:"reflectors"`
+	Rings      bool     `json:"rings"`
+	Steckers   int      `json:"steckers,omitempty"`
+	Candidates int      `json:"candidates,omitempty"`
+	Strategy   string   `json:"strategy,omitempty"` // See StrategyByName.
+	Scorer     string   `json:"scorer,omitempty"`   // See ScorerByName.
+	Model      string   `json:"model,omitempty"`    // An n-gram model file to score with instead.
+	Language   string   `json:"language,omitempty"` // For Scorer, see frequency.LookupLanguage.
+	Results    int      `json:"results"`
+	Shard      Shard    `json:"shard"`
+}
+
+// What a worker sends back for a job.
+type JobResults struct {
+	Job     Job      `json:"job"`
+	Results []Result `json:"results"`
+}
+
+/*
+	Divides the search described by opts into n jobs of about the same size.
+	Together they cover the whole key space. The scorer can't be passed on
+	to other processes, so it is left to RunJob, which uses the default
+	unless the job's Scorer or Model is set.
+
+	Without Steckers, merging the jobs' results gives the same results as
+	one Run. With Steckers each job hill climbs the best Candidates rotor
+	settings of its own shard, so between them the jobs try n times as many
+	settings as one Run, and may find pairs one Run would have missed.
+*/
+func Split(ciphertext string, opts Options, n int) ([]Job, error) {
+	opts.setDefaults()
+	space, err := newKeyspace(opts.Rotors, opts.Reflectors, opts.Rings, 0)
+	if err != nil {
+		return nil, err
+	}
+	size := space.size()
+	if n < 1 || n > size {
+		return nil, fmt.Errorf("can't split %d units of work into %d shards", size, n)
+	}
+
+	jobs := make([]Job, n)
+	for i := range jobs {
+		jobs[i] = Job{
+			Ciphertext: ciphertext,
+			Rotors:     opts.Rotors,
+			Reflectors: opts.Reflectors,
+			Rings:      opts.Rings,
+			Steckers:   opts.Steckers,
+			Candidates: opts.Candidates,
+			Strategy:   opts.Strategy.Name(),
+			Results:    opts.Results,
+			Shard:      Shard{i * size / n, (i + 1) * size / n},
+		}
+	}
+	return jobs, nil
+}
+
+/*
+	Searches the job's shard. Only the scorer, workers, progress and
+	checkpoint settings are taken from opts, the rest comes from the job.
+*/
+func RunJob(ctx context.Context, j Job, opts Options) (JobResults, error) {
+	opts, err := j.Options(opts)
+	if err != nil {
+		return JobResults{Job: j}, err
+	}
+	results, err := Run(ctx, j.Ciphertext, opts)
+	return JobResults{Job: j, Results: results}, err
+}
+
+/*
+	Options returns opts set up to search what the job describes. The
+	scorer is only replaced if the job names one, and the workers, progress
+	and checkpoint settings are left as they are.
+*/
+func (j Job) Options(opts Options) (Options, error) {
+	opts.Rotors = j.Rotors
+	opts.Reflectors = j.Reflectors
+	opts.Rings = j.Rings
+	opts.Steckers = j.Steckers
+	opts.Candidates = j.Candidates
+	if j.Model != "" {
+		m, err := ngram.Load(j.Model)
+		if err != nil {
+			return opts, err
+		}
+		opts.Scorer = m
+	} else if j.Scorer != "" {
+		l := frequency.English()
+		if j.Language != "" {
+			var err error
+			if l, err = frequency.LookupLanguage(j.Language); err != nil {
+				return opts, err
+			}
+		}
+		s, err := ScorerForLanguage(j.Scorer, l)
+		if err != nil {
+			return opts, err
+		}
+		opts.Scorer = s
+	}
+	if j.Strategy != "" {
+		s, err := StrategyByName(j.Strategy)
+		if err != nil {
+			return opts, err
+		}
+		opts.Strategy = s
+	}
+	opts.Results = j.Results
+	opts.Shard = j.Shard
+	return opts, nil
+}
+
+/*
+	Combines the results of the jobs from one Split into the best n, best
+	first. n defaults to 3, as Options.Results does. It is an error if the
+	jobs are from different searches, or if their shards don't cover the
+	whole key space exactly once.
+*/
+func Merge(n int, jobs ...JobResults) ([]Result, error) {
+	if err := checkShards(jobs); err != nil {
+		return nil, err
+	}
+	if n < 1 {
+		n = 3
+	}
+	best := container.NewSortedFixedSizeList(n)
+	for _, j := range jobs {
+		for i := range j.Results {
+			best.MaybeAdd(&j.Results[i])
+		}
+	}
+
+	var results []Result
+	for itr := best.Iterator(); itr.HasNext(); {
+		results = append(results, *itr.Next().(*Result))
+	}
+	return results, nil
+}
+
+// Checks that the jobs are from the same search and cover its key space once.
+func checkShards(jobs []JobResults) error {
+	if len(jobs) == 0 {
+		return errors.New("no results to merge")
+	}
+	search := jobs[0].Job
+	search.Shard = Shard{}
+	for i, j := range jobs[1:] {
+		j.Job.Shard = Shard{}
+		if !reflect.DeepEqual(j.Job, search) {
+			return fmt.Errorf("job %d is from a different search than job 0", i+1)
+		}
+	}
+
+	space, err := newKeyspace(search.Rotors, search.Reflectors, search.Rings, 0)
+	if err != nil {
+		return err
+	}
+	size := space.size()
+	shards := make([]Shard, len(jobs))
+	for i, j := range jobs {
+		first, last, err := j.Job.Shard.bounds(size)
+		if err != nil {
+			return fmt.Errorf("job %d: %s", i, err)
+		}
+		shards[i] = Shard{first, last}
+	}
+	sort.Slice(shards, func(a, b int) bool { return shards[a].First < shards[b].First })
+
+	next := 0
+	for _, s := range shards {
+		if s.First > next {
+			return fmt.Errorf("units %d-%d of %d are missing", next, s.First, size)
+		}
+		if s.First < next {
+			end := next
+			if s.Last < end {
+				end = s.Last
+			}
+			return fmt.Errorf("units %d-%d of %d are in more than one job", s.First, end, size)
+		}
+		next = s.Last
+	}
+	if next < size {
+		return fmt.Errorf("units %d-%d of %d are missing", next, size, size)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package crack
+
+import (
+	"context"
+	"testing"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+func TestSplit(t *testing.T) {
+	jobs, err := Split("ABC", Options{Reflectors: []string{"B"}}, 4)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(jobs) != 4 {
+		t.Fatalf("Expected 4 jobs, got %d", len(jobs))
+	}
+	next := 0
+	for _, j := range jobs {
+		if j.Shard.First != next || j.Shard.Last <= j.Shard.First {
+			t.Errorf("Expected a shard starting at %d, got %v", next, j.Shard)
+		}
+		next = j.Shard.Last
+	}
+	if next != 6*26 {
+		t.Errorf("Expected the shards to cover %d units, got %d", 6*26, next)
+	}
+
+	if _, err := Split("ABC", Options{}, 0); err == nil {
+		t.Errorf("Expected an error for no shards")
+	}
+	if _, err := Split("ABC", Options{Reflectors: []string{"B"}}, 6*26+1); err == nil {
+		t.Errorf("Expected an error for more shards than units")
+	}
+}
+
+func TestRunJobsAndMerge(t *testing.T) {
+	key := enigma.Key{Reflector: "B", Rotors: []string{"III", "I", "II"}, Positions: "QEV"}
+	encrypted := encrypt(t, key, englishText)
+
+	jobs, err := Split(encrypted, Options{Reflectors: []string{"B"}, Results: 2}, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var all []JobResults
+	for _, j := range jobs {
+		var last Progress
+		r, err := RunJob(context.Background(), j, Options{Progress: func(p Progress) { last = p }})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if n := int64(j.Shard.Last-j.Shard.First) * 26 * 26; last.Total != n {
+			t.Errorf("Expected progress out of %d configurations, got %s", n, last)
+		}
+		if len(r.Results) != 2 {
+			t.Errorf("Expected 2 results from each job, got %d", len(r.Results))
+		}
+		all = append(all, r)
+	}
+
+	results, err := Merge(2, all...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].Key.String() != key.String() || results[0].Plaintext != englishText {
+		t.Errorf("Expected %s, got %s %s", key, results[0].Key, results[0].Plaintext)
+	}
+}
+
+func TestShardsMatchRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping full test in short mode.")
+	}
+
+	key := enigma.Key{Reflector: "B", Rotors: []string{"II", "III", "I"}, Positions: "KDO"}
+	encrypted := encrypt(t, key, englishText)
+	opts := Options{Reflectors: []string{"B"}, Results: 10}
+
+	single, err := Run(context.Background(), encrypted, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	jobs, err := Split(encrypted, opts, 5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var all []JobResults
+	for _, j := range jobs {
+		r, err := RunJob(context.Background(), j, Options{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		all = append(all, r)
+	}
+	merged, err := Merge(10, all...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// Keys that score the same can come in either order.
+	if len(merged) != len(single) {
+		t.Fatalf("Expected %d results, got %d", len(single), len(merged))
+	}
+	for i := range single {
+		if merged[i].Score != single[i].Score {
+			t.Errorf("Expected result %d to score %f, got %f", i, single[i].Score, merged[i].Score)
+		}
+	}
+	if merged[0].Key.String() != key.String() {
+		t.Errorf("Expected %s first, got %s", key, merged[0].Key)
+	}
+}
+
+func TestMergeChecksJobs(t *testing.T) {
+	jobs, err := Split("ABC", Options{Reflectors: []string{"B"}}, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var all []JobResults
+	for i, j := range jobs {
+		r := Result{Plaintext: "ABC", Score: float64(i)}
+		all = append(all, JobResults{Job: j, Results: []Result{r}})
+	}
+
+	// The jobs can come in any order, and no number of results means the default.
+	results, err := Merge(0, all[2], all[0], all[1])
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(results) != 3 || results[0].Score != 2 {
+		t.Errorf("Expected the 3 results best first, got %v", results)
+	}
+
+	other := all[1]
+	other.Job.Ciphertext = "XYZ"
+	overlapping := all[1]
+	overlapping.Job.Shard.First--
+	for _, jobs := range [][]JobResults{
+		nil,
+		{all[0], all[1]},
+		{all[0], all[1], all[2], all[2]},
+		{all[0], other, all[2]},
+		{all[0], overlapping, all[2]},
+	} {
+		if _, err := Merge(3, jobs...); err == nil {
+			t.Errorf("Expected an error merging %d jobs", len(jobs))
+		}
+	}
+}
+
+func TestRunBadShard(t *testing.T) {
+	opts := Options{Reflectors: []string{"B"}, Shard: Shard{10, 6*26 + 1}}
+	if _, err := Run(context.Background(), "ABC", opts); err == nil {
+		t.Errorf("Expected an error for a shard outside the key space")
+	}
+}
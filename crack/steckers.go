@@ -0,0 +1,228 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package crack
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/mww/enigma-go/container"
+)
+
+/*
+	The substitution the rotors make at each letter of a message, so the
+	message can be decrypted with many different plugboards without
+	stepping a machine each time.
+*/
+type scrambler [][26]byte
+
+func newScrambler(r Result, length int) (scrambler, error) {
+	k := r.Key
+	k.Plugboard = ""
+	m, err := k.NewMachine()
+	if err != nil {
+		return nil, err
+	}
+	s := make(scrambler, length)
+	for i := range s {
+		p := m.Permutation()
+		for j, c := range p {
+			s[i][j] = byte(c - 'A')
+		}
+		m.Step('A')
+	}
+	return s, nil
+}
+
+// The plug each letter goes to, or the letter itself if it has no plug.
+type steckers [26]byte
+
+func noSteckers() steckers {
+	var s steckers
+	for i := range s {
+		s[i] = byte(i)
+	}
+	return s
+}
+
+func (s *steckers) pairs() int {
+	n := 0
+	for i, j := range s {
+		if int(j) > i {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *steckers) plug(a, b byte) {
+	s[a], s[b] = b, a
+}
+
+func (s *steckers) unplug(a byte) {
+	b := s[a]
+	s[a], s[b] = a, b
+}
+
+// The pairs in the form enigma.NewPlugboard accepts.
+func (s *steckers) String() string {
+	var buf bytes.Buffer
+	for i, j := range s {
+		if int(j) > i {
+			if buf.Len() > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteByte(byte('A' + i))
+			buf.WriteByte('A' + j)
+		}
+	}
+	return buf.String()
+}
+
+func (sc scrambler) decrypt(s *steckers, ciphertext string, buf []byte) string {
+	for i := 0; i < len(ciphertext); i++ {
+		buf[i] = 'A' + s[sc[i][s[ciphertext[i]-'A']]]
+	}
+	return string(buf)
+}
+
+/*
+	The changes to try for a pair of letters a and b, each given as the
+	plugboard after making it. Letters already plugged elsewhere have their
+	partners rearranged rather than being left without a plug, as in the
+	Weierud and Sullivan attack.
+*/
+func (s steckers) changes(a, b byte, maxPairs int) []steckers {
+	x, y := s[a], s[b]
+	switch {
+	case x == b:
+		s.unplug(a)
+		return []steckers{s}
+	case x == a && y == b:
+		if s.pairs() >= maxPairs {
+			return nil
+		}
+		s.plug(a, b)
+		return []steckers{s}
+	case x != a && y == b:
+		// a-x becomes a-b, or x-b with a left alone.
+		s.unplug(a)
+		first, second := s, s
+		first.plug(a, b)
+		second.plug(x, b)
+		return []steckers{first, second}
+	case x == a && y != b:
+		s.unplug(b)
+		first, second := s, s
+		first.plug(a, b)
+		second.plug(a, y)
+		return []steckers{first, second}
+	default:
+		// a-x and b-y become a-b and x-y, or a-y and b-x.
+		s.unplug(a)
+		s.unplug(b)
+		first, second := s, s
+		first.plug(a, b)
+		first.plug(x, y)
+		second.plug(a, y)
+		second.plug(b, x)
+		return []steckers{first, second}
+	}
+}
+
+/*
+	Finds the plugboard for a candidate rotor setting with opts.Strategy.
+*/
+func recoverSteckers(ciphertext string, candidate Result, opts Options) (Result, error) {
+	sc, err := newScrambler(candidate, len(ciphertext))
+	if err != nil {
+		return Result{}, err
+	}
+	p := &problem{sc: sc, ciphertext: ciphertext, maxPairs: opts.Steckers, score: opts.Scorer,
+		buf: make([]byte, len(ciphertext))}
+	s, score := opts.Strategy.search(p)
+
+	r := candidate
+	r.Key.Plugboard = s.String()
+	r.Plaintext = p.decrypt(&s)
+	r.Score = score
+	return r, nil
+}
+
+/*
+	Searches the rotor settings by index of coincidence with no plugs, then
+	hill climbs the plugboard for the best opts.Candidates of them. Only the
+	search of the rotor settings is reported as progress or checkpointed.
+*/
+func runWithSteckers(ctx context.Context, ciphertext string, opts Options) ([]Result, error) {
+	search := opts
+	search.Steckers = 0
+	search.Scorer = ScorerFunc(IndexOfCoincidence)
+	search.Results = opts.Candidates
+	candidates, err := run(ctx, ciphertext, search, describeSearch(ciphertext, opts))
+	if err != nil {
+		// Cancelled before any plugboard was looked for, so the best settings
+		// found so far are returned as they are.
+		if len(candidates) > opts.Results {
+			candidates = candidates[:opts.Results]
+		}
+		return candidates, err
+	}
+
+	work := make(chan Result)
+	go func() {
+		defer close(work)
+		for _, c := range candidates {
+			select {
+			case work <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	var firstErr error
+	best := container.NewSortedFixedSizeList(opts.Results)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range work {
+				r, err := recoverSteckers(ciphertext, c, opts)
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				} else if err == nil {
+					best.MaybeAdd(&r)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	var results []Result
+	for itr := best.Iterator(); itr.HasNext(); {
+		results = append(results, *itr.Next().(*Result))
+	}
+	return results, ctx.Err()
+}
@@ -0,0 +1,132 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package crack
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+// A few hundred letters of ordinary English for the plugboard to be found from.
+const longEnglishText = "THEREWASNOTHINGUNUSUALABOUTTHEMORNINGWHENTHELETTERARRIVED" +
+	"THEPOSTMANCAMEUPTHEPATHASHEALWAYSDIDANDLEFTITONTHESTEPWITHTHEOTHERS" +
+	"ITWASONLYLATERINTHEAFTERNOONWHENSHEOPENEDITTHATSHEUNDERSTOODWHATIT" +
+	"MEANTFORTHEFAMILYANDFORTHEHOUSEWHERETHEYHADLIVEDFORSOMANYYEARSHER" +
+	"BROTHERWOULDBECOMINGHOMEATTHEENDOFTHESUMMERANDHEWOULDBRINGNEWSOF" +
+	"THEWARWITHHIMWHETHERTHEYWANTEDTOHEARITORNOTSHEREADTHELETTERAGAIN" +
+	"ANDTHENPUTITAWAYINTHEDRAWEROFTHEKITCHENTABLE"
+
+func TestSteckerChanges(t *testing.T) {
+	s := noSteckers()
+	s.plug(0, 1) // AB
+	s.plug(2, 3) // CD
+	for _, test := range []struct {
+		a, b     byte
+		max      int
+		expected []string
+	}{
+		{0, 1, 10, []string{"CD"}},
+		{4, 5, 10, []string{"AB CD EF"}},
+		{4, 5, 2, nil},
+		{0, 4, 10, []string{"AE CD", "BE CD"}},
+		{4, 0, 10, []string{"AE CD", "BE CD"}},
+		{0, 2, 10, []string{"AC BD", "AD BC"}},
+	} {
+		var actual []string
+		for _, c := range s.changes(test.a, test.b, test.max) {
+			actual = append(actual, c.String())
+		}
+		if strings.Join(actual, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("Expected %v for %c%c, got %v", test.expected, 'A'+test.a, 'A'+test.b, actual)
+		}
+	}
+}
+
+func TestRecoverSteckers(t *testing.T) {
+	key := enigma.Key{Reflector: "B", Rotors: []string{"II", "V", "III"}, Rings: "AAA",
+		Positions: "KQD", Plugboard: "AM BT CL DH EX FQ GV IZ JS KP"}
+	encrypted := encrypt(t, key, longEnglishText)
+
+	opts := Options{Steckers: 10}
+	opts.setDefaults()
+	candidate := Result{Key: key}
+	candidate.Key.Plugboard = ""
+	r, err := recoverSteckers(encrypted, candidate, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if r.Plaintext != longEnglishText {
+		t.Errorf("Expected %s, got %s %s", longEnglishText, r.Key.Plugboard, r.Plaintext)
+	}
+}
+
+func TestRunSteckers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping full test in short mode.")
+	}
+
+	key := enigma.Key{Reflector: "B", Rotors: []string{"III", "I", "II"},
+		Positions: "HDX", Plugboard: "AR GK OX QW EN TZ"}
+	encrypted := encrypt(t, key, longEnglishText)
+
+	results, err := Run(context.Background(), encrypted, Options{Reflectors: []string{"B"}, Steckers: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if results[0].Plaintext != longEnglishText {
+		t.Errorf("Expected %s, got %s %s", longEnglishText, results[0].Key, results[0].Plaintext)
+	}
+}
+
+func TestRunSteckersBelowResults(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping full test in short mode.")
+	}
+
+	// With 8 plug pairs the right rotor setting is only 8th by index of
+	// coincidence, below the 3 results returned, so it must be kept as a
+	// candidate until its plugboard has been found.
+	key := enigma.Key{Reflector: "B", Rotors: []string{"III", "I", "II"},
+		Positions: "KQD", Plugboard: "AR GK OX QW EN TZ BY CU"}
+	encrypted := encrypt(t, key, longEnglishText)
+
+	opts := Options{Reflectors: []string{"B"}, Scorer: ScorerFunc(IndexOfCoincidence), Results: 10}
+	candidates, err := Run(context.Background(), encrypted, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	rank := -1
+	for i, c := range candidates {
+		if c.Key.Positions == key.Positions && strings.Join(c.Key.Rotors, "-") == "III-I-II" {
+			rank = i
+		}
+	}
+	if rank < 3 {
+		t.Fatalf("Expected the rotor setting to rank below 3 by index of coincidence, got %d", rank)
+	}
+
+	opts = Options{Reflectors: []string{"B"}, Steckers: 10, Candidates: 10, Results: 3}
+	results, err := Run(context.Background(), encrypted, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(results) != 3 || results[0].Plaintext != longEnglishText {
+		t.Errorf("Expected %s, got %v", longEnglishText, results)
+	}
+}
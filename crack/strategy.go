@@ -0,0 +1,223 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package crack
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/mww/enigma-go/ngram"
+)
+
+/*
+	A Strategy is how the plugboard of a rotor setting is looked for, one of
+	HillClimb, RandomRestart or Annealing. The strategies only differ in how
+	they move between plugboards, so they can be compared on the same
+	message by running it with each. The zero value is HillClimb.
+*/
+type Strategy struct {
+	name   string
+	search func(p *problem) (steckers, float64)
+}
+
+// Name returns the name StrategyByName knows the strategy by.
+func (s Strategy) Name() string {
+	if s.search == nil {
+		return "hillclimb"
+	}
+	return s.name
+}
+
+// The plugboard of one rotor setting, for a strategy to find.
+type problem struct {
+	sc         scrambler
+	ciphertext string
+	maxPairs   int
+	score      Scorer
+	buf        []byte
+
+	// Reused to score plaintexts with an n-gram model, see beats.
+	running      *ngram.Running
+	runningModel *ngram.Model
+}
+
+func (p *problem) decrypt(s *steckers) string {
+	return p.sc.decrypt(s, p.ciphertext, p.buf)
+}
+
+/*
+	Improves the plugboard one change at a time until no change to any pair
+	of letters scores better, and returns it with its score.
+*/
+func (p *problem) hillClimb(s steckers, score Scorer) (steckers, float64) {
+	best := score.Score(p.decrypt(&s))
+	for improved := true; improved; {
+		improved = false
+		for a := byte(0); a < 26; a++ {
+			for b := a + 1; b < 26; b++ {
+				for _, c := range s.changes(a, b, p.maxPairs) {
+					if v, ok := p.beats(&c, score, best); ok {
+						s, best, improved = c, v, true
+					}
+				}
+			}
+		}
+	}
+	return s, best
+}
+
+/*
+	Scores the plaintext of a plugboard if it scores above best. An n-gram
+	model is scored a letter at a time with a running score. Every run of
+	letters adds a log probability of at most 0, so the plugboard is given
+	up on as soon as the total falls below what best needs.
+*/
+func (p *problem) beats(s *steckers, score Scorer, best float64) (float64, bool) {
+	m := ngramModel(score)
+	if m == nil || len(p.ciphertext) < m.N {
+		v := score.Score(p.decrypt(s))
+		return v, v > best
+	}
+	if p.running == nil || p.runningModel != m {
+		p.running, p.runningModel = m.Running(), m
+	}
+	r := p.running
+	r.Reset()
+	limit := best * float64(len(p.ciphertext)-m.N+1)
+	for i := 0; i < len(p.ciphertext); i++ {
+		r.Add('A' + s[p.sc[i][s[p.ciphertext[i]-'A']]])
+		if r.Sum() < limit {
+			return 0, false
+		}
+	}
+	return r.Score(), r.Score() > best
+}
+
+// The n-gram model a scorer scores with, if it is one.
+func ngramModel(s Scorer) *ngram.Model {
+	if n, ok := s.(namedScorer); ok {
+		s = n.Scorer
+	}
+	m, _ := s.(*ngram.Model)
+	return m
+}
+
+/*
+	Hill climbs by index of coincidence and then by the scorer, which needs
+	more of the plugboard right before it can tell plugboards apart.
+*/
+func (p *problem) climbFrom(s steckers) (steckers, float64) {
+	s, _ = p.hillClimb(s, ScorerFunc(IndexOfCoincidence))
+	return p.hillClimb(s, p.score)
+}
+
+// A plugboard with n pairs picked at random.
+func randomSteckers(r *rand.Rand, n int) steckers {
+	s := noSteckers()
+	letters := r.Perm(26)
+	for i := 0; i < n; i++ {
+		s.plug(byte(letters[2*i]), byte(letters[2*i+1]))
+	}
+	return s
+}
+
+/*
+	HillClimb starts with no plugs and makes whichever change improves the
+	score until none do. It is fast and deterministic, but stops at the
+	first plugboard that no single change improves.
+*/
+func HillClimb() Strategy {
+	return Strategy{"hillclimb", func(p *problem) (steckers, float64) {
+		return p.climbFrom(noSteckers())
+	}}
+}
+
+/*
+	RandomRestart hill climbs from no plugs and then from restarts random
+	plugboards, keeping the best, so a climb that gets stuck can be made up
+	for by another.
+*/
+func RandomRestart(restarts int, seed int64) Strategy {
+	return Strategy{"restart", func(p *problem) (steckers, float64) {
+		r := rand.New(rand.NewSource(seed))
+		best, bestScore := p.climbFrom(noSteckers())
+		for i := 0; i < restarts; i++ {
+			if s, score := p.climbFrom(randomSteckers(r, p.maxPairs)); score > bestScore {
+				best, bestScore = s, score
+			}
+		}
+		return best, bestScore
+	}}
+}
+
+/*
+	Annealing makes random changes to the plugboard, taking ones that make
+	the score worse with a chance that falls as the temperature cools from
+	start to end over steps changes, which lets it get out of plugboards a
+	hill climb would stop at. The best plugboard seen is then hill climbed
+	in case it is a change or two away from a better one.
+*/
+func Annealing(start, end float64, steps int, seed int64) Strategy {
+	return Strategy{"anneal", func(p *problem) (steckers, float64) {
+		return p.anneal(start, end, steps, seed)
+	}}
+}
+
+func (p *problem) anneal(start, end float64, steps int, seed int64) (steckers, float64) {
+	r := rand.New(rand.NewSource(seed))
+	s := noSteckers()
+	current := p.score.Score(p.decrypt(&s))
+	best, bestScore := s, current
+
+	temperature := start
+	cooling := math.Pow(end/start, 1/float64(steps))
+	for i := 0; i < steps; i, temperature = i+1, temperature*cooling {
+		x, y := byte(r.Intn(26)), byte(r.Intn(26))
+		if x == y {
+			continue
+		}
+		changes := s.changes(x, y, p.maxPairs)
+		if len(changes) == 0 {
+			continue
+		}
+		c := changes[r.Intn(len(changes))]
+		v := p.score.Score(p.decrypt(&c))
+		if v > current || r.Float64() < math.Exp((v-current)/temperature) {
+			s, current = c, v
+			if v > bestScore {
+				best, bestScore = c, v
+			}
+		}
+	}
+	return p.hillClimb(best, p.score)
+}
+
+// The strategies by name, with settings that suit BigramScore.
+var strategies = map[string]Strategy{
+	"hillclimb": HillClimb(),
+	"restart":   RandomRestart(5, 1),
+	"anneal":    Annealing(0.02, 0.0002, 20000, 1),
+}
+
+// StrategyByName returns hillclimb, restart or anneal with default settings.
+func StrategyByName(name string) (Strategy, error) {
+	if s, ok := strategies[strings.ToLower(name)]; ok {
+		return s, nil
+	}
+	return Strategy{}, fmt.Errorf("unknown strategy %q, expected hillclimb, restart or anneal", name)
+}
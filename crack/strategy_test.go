@@ -0,0 +1,114 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package crack
+
+import (
+	"math/rand"
+	"testing"
+
+	enigma "github.com/mww/enigma-go"
+	"github.com/mww/enigma-go/ngram"
+)
+
+func TestStrategies(t *testing.T) {
+	key := enigma.Key{Reflector: "B", Rotors: []string{"II", "V", "III"}, Rings: "AAA",
+		Positions: "KQD", Plugboard: "AM BT CL DH EX FQ GV IZ JS KP"}
+	encrypted := encrypt(t, key, longEnglishText)
+	candidate := Result{Key: key}
+	candidate.Key.Plugboard = ""
+
+	for _, name := range []string{"hillclimb", "restart", "anneal"} {
+		strategy, err := StrategyByName(name)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if strategy.Name() != name {
+			t.Errorf("Expected %s, got %s", name, strategy.Name())
+		}
+
+		opts := Options{Steckers: 10, Strategy: strategy}
+		opts.setDefaults()
+		r, err := recoverSteckers(encrypted, candidate, opts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if r.Plaintext != longEnglishText {
+			t.Errorf("Expected %s to find the plaintext, got %s %s", name, r.Key.Plugboard, r.Plaintext)
+		}
+	}
+
+	if _, err := StrategyByName("guess"); err == nil {
+		t.Errorf("Expected an error for an unknown strategy")
+	}
+	if name := (Strategy{}).Name(); name != "hillclimb" {
+		t.Errorf("Expected the zero Strategy to be hillclimb, got %s", name)
+	}
+}
+
+func TestHillClimbRunningScore(t *testing.T) {
+	key := enigma.Key{Reflector: "B", Rotors: []string{"II", "V", "III"}, Rings: "AAA",
+		Positions: "KQD", Plugboard: "AM BT CL DH EX FQ GV IZ JS KP"}
+	encrypted := encrypt(t, key, longEnglishText)
+	candidate := Result{Key: key}
+	candidate.Key.Plugboard = ""
+
+	// Giving up on plugboards part way through must not change the climb.
+	m, _ := ngram.English(4)
+	var results []Result
+	for _, scorer := range []Scorer{m, ScorerFunc(m.Score)} {
+		opts := Options{Steckers: 10, Scorer: scorer}
+		opts.setDefaults()
+		r, err := recoverSteckers(encrypted, candidate, opts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		results = append(results, r)
+	}
+	if results[0].Key.Plugboard != results[1].Key.Plugboard || results[0].Score != results[1].Score {
+		t.Errorf("Expected the same plugboard, got %s %f and %s %f", results[0].Key.Plugboard,
+			results[0].Score, results[1].Key.Plugboard, results[1].Score)
+	}
+}
+
+func TestRandomSteckers(t *testing.T) {
+	s := randomSteckers(rand.New(rand.NewSource(1)), 10)
+	if s.pairs() != 10 {
+		t.Errorf("Expected 10 pairs, got %s", s.String())
+	}
+	if _, err := enigma.NewPlugboard(s.String()); err != nil {
+		t.Errorf("Expected a valid plugboard, got %s", err)
+	}
+}
+
+// Compares how long each strategy takes to find the plugboard of one setting.
+func BenchmarkStrategies(b *testing.B) {
+	key := enigma.Key{Reflector: "B", Rotors: []string{"II", "V", "III"},
+		Positions: "KQD", Plugboard: "AM BT CL DH EX FQ GV IZ JS KP"}
+	encrypted := encrypt(b, key, longEnglishText)
+	candidate := Result{Key: key}
+	candidate.Key.Plugboard = ""
+
+	for _, name := range []string{"hillclimb", "restart", "anneal"} {
+		strategy, _ := StrategyByName(name)
+		opts := Options{Steckers: 10, Strategy: strategy}
+		opts.setDefaults()
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				recoverSteckers(encrypted, candidate, opts)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+/*
+	Package crib places probable plaintext, a crib, under a ciphertext.
+
+	An Enigma never encrypts a letter to itself, so a crib can't lie where
+	any of its letters is above the same letter of the ciphertext. For a
+	crib of a dozen letters that rules out most places.
+*/
+package crib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+	Positions returns every offset into the ciphertext at which the crib
+	can lie, those without a collision, in order.
+*/
+func Positions(ciphertext, crib string) ([]int, error) {
+	ciphertext, crib, err := check(ciphertext, crib)
+	if err != nil {
+		return nil, err
+	}
+	positions := []int{}
+	for offset := 0; offset+len(crib) <= len(ciphertext); offset++ {
+		if len(Collisions(ciphertext, crib, offset)) == 0 {
+			positions = append(positions, offset)
+		}
+	}
+	return positions, nil
+}
+
+/*
+	Collisions returns the indexes into the crib of the letters that are
+	the same as the ciphertext above them when the crib is at the offset.
+	Letters that run off either end of the ciphertext don't collide, and
+	case doesn't matter.
+*/
+func Collisions(ciphertext, crib string, offset int) []int {
+	ciphertext, crib = strings.ToUpper(ciphertext), strings.ToUpper(crib)
+	var collisions []int
+	for i := 0; i < len(crib); i++ {
+		j := offset + i
+		if j >= 0 && j < len(ciphertext) && ciphertext[j] == crib[i] {
+			collisions = append(collisions, i)
+		}
+	}
+	return collisions
+}
+
+/*
+	Show draws the crib at the offset under the ciphertext, with a line
+	marking any collisions:
+
+		QFZWRWIVTYRESXBFOGKUHQBAISE
+		     WETTERVORHERSAGE
+		         ^
+
+	The crib must lie wholly under the ciphertext.
+*/
+func Show(ciphertext, crib string, offset int) (string, error) {
+	ciphertext, crib, err := check(ciphertext, crib)
+	if err != nil {
+		return "", err
+	}
+	if offset < 0 || offset+len(crib) > len(ciphertext) {
+		return "", fmt.Errorf("offset %d is outside the message, it must be 0-%d", offset, len(ciphertext)-len(crib))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(ciphertext)
+	buf.WriteByte('\n')
+	buf.WriteString(strings.Repeat(" ", offset))
+	buf.WriteString(crib)
+	buf.WriteByte('\n')
+	if collisions := Collisions(ciphertext, crib, offset); len(collisions) > 0 {
+		marks := []byte(strings.Repeat(" ", offset+collisions[len(collisions)-1]+1))
+		for _, i := range collisions {
+			marks[offset+i] = '^'
+		}
+		buf.Write(marks)
+		buf.WriteByte('\n')
+	}
+	return buf.String(), nil
+}
+
+func check(ciphertext, crib string) (string, string, error) {
+	ciphertext, crib = strings.ToUpper(ciphertext), strings.ToUpper(crib)
+	if strings.IndexFunc(ciphertext, notLetter) >= 0 || strings.IndexFunc(crib, notLetter) >= 0 {
+		return "", "", errors.New("the message and crib must only contain the letters A-Z")
+	}
+	if crib == "" {
+		return "", "", errors.New("the crib is empty")
+	}
+	if len(crib) > len(ciphertext) {
+		return "", "", errors.New("the crib is longer than the message")
+	}
+	return ciphertext, crib, nil
+}
+
+func notLetter(r rune) bool {
+	return r < 'A' || r > 'Z'
+}
@@ -0,0 +1,100 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package crib
+
+import (
+	"reflect"
+	"testing"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+func TestPositions(t *testing.T) {
+	positions, err := Positions("ABCDE", "ax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// AX collides with ABCDE only at offset 0.
+	if expected := []int{1, 2, 3}; !reflect.DeepEqual(positions, expected) {
+		t.Errorf("Expected %v, got %v", expected, positions)
+	}
+}
+
+func TestPositionsIncludesTheRightOne(t *testing.T) {
+	m, err := (&enigma.Key{Reflector: "B", Rotors: []string{"II", "IV", "V"}, Positions: "BLA", Plugboard: "AV BS CG"}).NewMachine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := m.Encrypt("ANXBDUXWETTERVORHERSAGEXNORDSEEXREGENXWINDXSTAERKEXFUENF")
+	positions, err := Positions(ciphertext, "WETTERVORHERSAGE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, p := range positions {
+		found = found || p == 6
+	}
+	if !found {
+		t.Errorf("Expected 6 among %v", positions)
+	}
+	if len(positions) >= len(ciphertext)-len("WETTERVORHERSAGE") {
+		t.Errorf("Expected some positions to be ruled out, got %v", positions)
+	}
+}
+
+func TestCollisions(t *testing.T) {
+	if c := Collisions("ABCABC", "BXA", 1); !reflect.DeepEqual(c, []int{0, 2}) {
+		t.Errorf("Expected [0 2], got %v", c)
+	}
+	if c := Collisions("qfz", "QxZ", 0); !reflect.DeepEqual(c, []int{0, 2}) {
+		t.Errorf("Expected [0 2] whatever the case, got %v", c)
+	}
+	// Past either end nothing collides.
+	if c := Collisions("ABC", "CAB", -2); c != nil {
+		t.Errorf("Expected no collisions, got %v", c)
+	}
+}
+
+func TestShow(t *testing.T) {
+	expected := "ABCABC\n BXA\n ^ ^\n"
+	if s, err := Show("ABCABC", "BXA", 1); err != nil || s != expected {
+		t.Errorf("Expected %q, got %q, %v", expected, s, err)
+	}
+	expected = "ABCABC\n   XYZ\n"
+	if s, err := Show("ABCABC", "XYZ", 3); err != nil || s != expected {
+		t.Errorf("Expected %q, got %q, %v", expected, s, err)
+	}
+}
+
+func TestShowErrors(t *testing.T) {
+	// The crib would start before the message or run past its end.
+	for _, offset := range []int{-1, -3, 4, 10} {
+		if s, err := Show("ABCABC", "XYZ", offset); err == nil {
+			t.Errorf("Expected offset %d to fail, got %q", offset, s)
+		}
+	}
+	if _, err := Show("ABCABC", "", 0); err == nil {
+		t.Errorf("Expected an empty crib to fail")
+	}
+}
+
+func TestPositionsErrors(t *testing.T) {
+	for _, args := range [][2]string{{"ABC", ""}, {"AB", "ABC"}, {"AB C", "A"}, {"ABC", "A1"}} {
+		if _, err := Positions(args[0], args[1]); err == nil {
+			t.Errorf("Expected Positions(%q, %q) to fail", args[0], args[1])
+		}
+	}
+}
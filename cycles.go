@@ -0,0 +1,146 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package enigma
+
+import (
+	"bytes"
+	"sort"
+)
+
+const numPositions = 26 * 26 * 26
+
+/*
+	A Permutation holds the letter each letter is enciphered to, indexed by
+	the input letter's position in the alphabet.
+*/
+type Permutation [26]rune
+
+/*
+	Permutation returns the substitution the machine will perform on the next
+	key press, after the rotors have stepped. The machine itself is not
+	changed.
+*/
+func (m *Machine) Permutation() Permutation {
+	c := *m
+	c.moveRotors()
+	return c.Substitution()
+}
+
+/*
+	Substitution returns the substitution the machine performs with the
+	rotors where they are now, without stepping them first as a key press
+	would.
+*/
+func (m *Machine) Substitution() Permutation {
+	var p Permutation
+	for i := range p {
+		p[i] = LETTERS[m.encode(int32(i))]
+	}
+	return p
+}
+
+/*
+	Cycles returns the cycles of the permutation, each one written starting
+	with its lowest letter, in the order of their first letters.
+*/
+func (p Permutation) Cycles() []string {
+	var seen [26]bool
+	var cycles []string
+	for i := range p {
+		var cycle []rune
+		for j := int32(i); !seen[j]; j = p[j] - 'A' {
+			seen[j] = true
+			cycle = append(cycle, LETTERS[j])
+		}
+		if len(cycle) > 0 {
+			cycles = append(cycles, string(cycle))
+		}
+	}
+	return cycles
+}
+
+// CycleLengths returns the length of each cycle, longest first.
+func (p Permutation) CycleLengths() []int {
+	cycles := p.Cycles()
+	lengths := make([]int, len(cycles))
+	for i, c := range cycles {
+		lengths[i] = len(c)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(lengths)))
+	return lengths
+}
+
+// String writes the permutation in cycle notation, e.g. (AB)(CD)...
+func (p Permutation) String() string {
+	var buf bytes.Buffer
+	for _, c := range p.Cycles() {
+		buf.WriteRune('(')
+		buf.WriteString(c)
+		buf.WriteRune(')')
+	}
+	return buf.String()
+}
+
+/*
+	Period steps a copy of the machine from its current position until the
+	rotor positions repeat. It returns the number of key presses made before
+	the rotors enter their cycle, which is only non-zero when starting from a
+	position the double step makes unreachable, and the length of the cycle.
+*/
+func (m *Machine) Period() (tail, period int) {
+	var visited [numPositions]int
+	c := *m
+	for count := 1; ; count++ {
+		i := c.positionIndex()
+		if visited[i] != 0 {
+			return visited[i] - 1, count - visited[i]
+		}
+		visited[i] = count
+		c.moveRotors()
+	}
+}
+
+/*
+	UnreachablePositions returns every rotor position, as window letters, that
+	can be set by hand but that the machine never steps into, because the
+	middle rotor always double steps straight past it.
+*/
+func (m *Machine) UnreachablePositions() []string {
+	var reached [numPositions]bool
+	c := *m
+	for i := 0; i < numPositions; i++ {
+		c.setPositionIndex(i)
+		c.moveRotors()
+		reached[c.positionIndex()] = true
+	}
+
+	var unreachable []string
+	for i, r := range reached {
+		if !r {
+			c.setPositionIndex(i)
+			unreachable = append(unreachable, c.Positions())
+		}
+	}
+	return unreachable
+}
+
+func (m *Machine) positionIndex() int {
+	return int(m.p1*26*26 + m.p2*26 + m.p3)
+}
+
+func (m *Machine) setPositionIndex(i int) {
+	m.p1, m.p2, m.p3 = int32(i/(26*26)), int32(i/26%26), int32(i%26)
+}
@@ -0,0 +1,106 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package enigma
+
+import "testing"
+
+func assertEqualsInt(t *testing.T, expected, actual int) {
+	if expected != actual {
+		t.Errorf("Expected %d, got %d\n", expected, actual)
+	}
+}
+
+func TestPermutation(t *testing.T) {
+	m := NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'A', 'A')
+	p := m.Permutation()
+	assertEqualsRune(t, 'B', p[0])
+	assertEqualsRune(t, 'A', p[1])
+	if m.Positions() != "AAA" {
+		t.Errorf("Expected AAA, got %s", m.Positions())
+	}
+
+	// Without a plugboard the reflector always produces 13 swapped pairs.
+	lengths := p.CycleLengths()
+	assertEqualsInt(t, 13, len(lengths))
+	for _, l := range lengths {
+		assertEqualsInt(t, 2, l)
+	}
+	if s := p.String(); s[:4] != "(AB)" {
+		t.Errorf("Expected (AB) first, got %s", s)
+	}
+}
+
+func TestSubstitution(t *testing.T) {
+	// At AAB it is what the first key press from AAA enciphers with.
+	m := NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'A', 'A')
+	p := m.Permutation()
+	m.SetPositions('A', 'A', 'B')
+	if s := m.Substitution(); s != p {
+		t.Errorf("Expected %s, got %s", p, s)
+	}
+	if m.Positions() != "AAB" {
+		t.Errorf("Expected AAB, got %s", m.Positions())
+	}
+}
+
+func TestCycles(t *testing.T) {
+	var p Permutation
+	for i := range p {
+		p[i] = LETTERS[(i+1)%26]
+	}
+	p[25], p[24] = 'Y', 'A'
+
+	cycles := p.Cycles()
+	if len(cycles) != 2 || cycles[0] != "ABCDEFGHIJKLMNOPQRSTUVWXY" || cycles[1] != "Z" {
+		t.Errorf("Unexpected cycles %v", cycles)
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	m := NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'A', 'A')
+	tail, period := m.Period()
+	assertEqualsInt(t, 0, tail)
+	assertEqualsInt(t, 26*25*26, period)
+
+	// A right rotor turning over at Z still carries into the middle rotor.
+	m = NewMachine(Rotor1(), Rotor2(), Rotor5(), ReflectorB(), 'A', 'A', 'A')
+	tail, period = m.Period()
+	assertEqualsInt(t, 26*25*26, period)
+
+	// The middle rotor is at its notch, so the first key press double steps.
+	m = NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'E', 'A')
+	tail, period = m.Period()
+	assertEqualsInt(t, 1, tail)
+	assertEqualsInt(t, 26*25*26, period)
+
+	// Two notches on the right rotor halve the period.
+	m = NewMachine(Rotor1(), Rotor2(), Rotor6(), ReflectorB(), 'A', 'A', 'A')
+	tail, period = m.Period()
+	assertEqualsInt(t, 26*25*13, period)
+}
+
+func TestUnreachablePositions(t *testing.T) {
+	m := NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'A', 'A')
+	unreachable := m.UnreachablePositions()
+
+	// The middle rotor only reaches E as the right rotor moves to W.
+	assertEqualsInt(t, 26*25, len(unreachable))
+	for _, p := range unreachable {
+		if p[1] != 'E' || p[2] == 'W' {
+			t.Errorf("Did not expect %s to be unreachable", p)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+/*
+	Package diagram draws the state of an Enigma machine as an SVG image.
+*/
+package diagram
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+const (
+	spacing     = 18  // Vertical distance between two contacts.
+	top         = 70  // Space above the contacts for labels.
+	margin      = 20  // Space around the whole image.
+	columnWidth = 110 // Width of each component.
+	gap         = 30  // Space between two components.
+
+	wireColor  = "#bbbbbb"
+	pathColor  = "#d62728"
+	keyColor   = "#2ca02c"
+	lampColor  = "#ffd700"
+	labelStyle = `font-family="monospace" font-size="12"`
+)
+
+// The components from left to right, the keyboard and lamps are on the far right.
+var columns = []enigma.Component{
+	enigma.ReflectorComponent,
+	enigma.LeftRotorComponent,
+	enigma.MiddleRotorComponent,
+	enigma.RightRotorComponent,
+	enigma.PlugboardComponent,
+}
+
+/*
+	Write draws the machine as an SVG image: the reflector, the 3 rotors with
+	their window letters, ring settings and current wiring, the plugboard and
+	the keyboard. If trace is not nil the path of its key press is drawn on
+	top, from the key that was pressed to the lamp that lit. The trace should
+	come from the machine's most recent Step.
+*/
+func Write(w io.Writer, m *enigma.Machine, trace *enigma.Trace) error {
+	d := drawing{w: w}
+	width := 2*margin + len(columns)*(columnWidth+gap) + 40
+	height := top + 26*spacing + margin
+
+	d.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		width, height, width, height)
+	d.printf(`<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+
+	r1, r2, r3 := m.Rotors()
+	rotors := map[enigma.Component]*enigma.Rotor{
+		enigma.LeftRotorComponent:   r1,
+		enigma.MiddleRotorComponent: r2,
+		enigma.RightRotorComponent:  r3,
+	}
+	positions, rings := m.Positions(), m.Rings()
+
+	for i, c := range columns {
+		x := left(i)
+		wiring := m.Wiring(c)
+		d.printf(`<g id="%s">`+"\n", strings.Replace(c.String(), " ", "-", -1))
+		d.printf(`<rect x="%d" y="%d" width="%d" height="%d" fill="#f4f4f4" stroke="black"/>`+"\n",
+			x, top-spacing/2, columnWidth, 26*spacing)
+
+		switch c {
+		case enigma.ReflectorComponent:
+			d.label(x, top-40, m.Reflector().String())
+			for j, to := range wiring {
+				if int32(j) < to-'A' {
+					d.loop(x+columnWidth, y(j), y(int(to-'A')), wireColor, 1)
+				}
+			}
+		case enigma.PlugboardComponent:
+			d.label(x, top-40, "Plugboard")
+			if p := m.Plugboard(); p != nil {
+				d.label(x, top-25, p.String())
+			}
+			for j, to := range wiring {
+				d.line(x, y(int(to-'A')), x+columnWidth, y(j), wireColor, 1)
+			}
+		default:
+			rotor := rotors[c]
+			n := 0
+			switch c {
+			case enigma.MiddleRotorComponent:
+				n = 1
+			case enigma.RightRotorComponent:
+				n = 2
+			}
+			d.label(x, top-40, rotor.String())
+			d.label(x, top-25, fmt.Sprintf("window %c  ring %c", positions[n], rings[n]))
+			for j, to := range wiring {
+				d.line(x, y(int(to-'A')), x+columnWidth, y(j), wireColor, 1)
+			}
+		}
+
+		// Contacts on each side, labelled with their letter.
+		for j := 0; j < 26; j++ {
+			if c != enigma.ReflectorComponent {
+				d.printf(`<circle cx="%d" cy="%d" r="2"/>`+"\n", x, y(j))
+			}
+			d.printf(`<circle cx="%d" cy="%d" r="2"/>`+"\n", x+columnWidth, y(j))
+		}
+		d.printf("</g>\n")
+
+		// Wires joining this component to the next.
+		for j := 0; j < 26; j++ {
+			d.line(x+columnWidth, y(j), x+columnWidth+gap, y(j), wireColor, 1)
+		}
+	}
+
+	// The keyboard and lamps.
+	keys := left(len(columns))
+	d.printf(`<g id="keyboard">` + "\n")
+	for j := 0; j < 26; j++ {
+		fill := "white"
+		if trace != nil && trace.Lamp() == enigma.LETTERS[j] {
+			fill = lampColor
+		} else if trace != nil && trace.Key() == enigma.LETTERS[j] {
+			fill = keyColor
+		}
+		d.printf(`<circle cx="%d" cy="%d" r="8" fill="%s" stroke="black"/>`+"\n",
+			keys+10, y(j), fill)
+		d.printf(`<text x="%d" y="%d" text-anchor="middle" %s>%c</text>`+"\n",
+			keys+10, y(j)+4, labelStyle, enigma.LETTERS[j])
+	}
+	d.printf("</g>\n")
+
+	if trace != nil && len(trace.Hops) > 0 {
+		d.path(trace)
+	}
+
+	d.printf("</svg>\n")
+	return d.err
+}
+
+// Keeps the first error so the drawing code doesn't have to check each write.
+type drawing struct {
+	w   io.Writer
+	err error
+}
+
+func (d *drawing) printf(format string, args ...interface{}) {
+	if d.err == nil {
+		_, d.err = fmt.Fprintf(d.w, format, args...)
+	}
+}
+
+func (d *drawing) label(x, y int, text string) {
+	d.printf(`<text x="%d" y="%d" %s>%s</text>`+"\n", x, y, labelStyle, escape(text))
+}
+
+func (d *drawing) line(x1, y1, x2, y2 int, color string, width int) {
+	d.printf(`<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="%d"/>`+"\n",
+		x1, y1, x2, y2, color, width)
+}
+
+// Draws a wire that leaves the right side of the reflector and comes back.
+func (d *drawing) loop(x, y1, y2 int, color string, width int) {
+	depth := columnWidth * (y2 - y1) / (26 * spacing)
+	if depth < 0 {
+		depth = -depth
+	}
+	d.printf(`<path d="M %d %d C %d %d %d %d %d %d" fill="none" stroke="%s" stroke-width="%d"/>`+"\n",
+		x, y1, x-depth-10, y1, x-depth-10, y2, x, y2, color, width)
+}
+
+// Draws the path of the traced key press over the wiring.
+func (d *drawing) path(trace *enigma.Trace) {
+	d.printf(`<g id="path">` + "\n")
+	keys := left(len(columns))
+	x, letter := keys, int(trace.Key()-'A')
+	d.line(keys+2, y(letter), x, y(letter), pathColor, 3)
+
+	reflected := false
+	for _, h := range trace.Hops {
+		column := columnOf(h.Component)
+		in, out := int(h.In-'A'), int(h.Out-'A')
+		right := left(column) + columnWidth
+		switch {
+		case h.Component == enigma.ReflectorComponent:
+			d.line(x, y(in), right, y(in), pathColor, 3)
+			d.loop(right, y(in), y(out), pathColor, 3)
+			x = right
+			reflected = true
+		case !reflected:
+			d.line(x, y(in), right, y(in), pathColor, 3)
+			d.line(right, y(in), left(column), y(out), pathColor, 3)
+			x = left(column)
+		default:
+			d.line(x, y(in), left(column), y(in), pathColor, 3)
+			d.line(left(column), y(in), right, y(out), pathColor, 3)
+			x = right
+		}
+	}
+	d.line(x, y(int(trace.Lamp()-'A')), keys+2, y(int(trace.Lamp()-'A')), pathColor, 3)
+	d.printf("</g>\n")
+}
+
+func columnOf(c enigma.Component) int {
+	for i, column := range columns {
+		if column == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// The x coordinate of the left side of the given column.
+func left(column int) int {
+	return margin + column*(columnWidth+gap)
+}
+
+// The y coordinate of the contact for the given letter index.
+func y(letter int) int {
+	return top + letter*spacing
+}
+
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,106 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package diagram
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+// Checks the output is well formed XML and returns the ids of its groups.
+func parse(t *testing.T, svg []byte) []string {
+	var ids []string
+	decoder := xml.NewDecoder(bytes.NewReader(svg))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return ids
+		}
+		if err != nil {
+			t.Fatalf("Invalid SVG: %s", err)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "g" {
+			for _, a := range start.Attr {
+				if a.Name.Local == "id" {
+					ids = append(ids, a.Value)
+				}
+			}
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	m := enigma.NewMachine(enigma.Rotor1(), enigma.Rotor2(), enigma.Rotor3(),
+		enigma.ReflectorB(), 'A', 'D', 'U')
+	m.SetRings('A', 'B', 'C')
+
+	var buf bytes.Buffer
+	if err := Write(&buf, m, nil); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	ids := parse(t, buf.Bytes())
+	expected := "reflector,left-rotor,middle-rotor,right-rotor,plugboard,keyboard"
+	if strings.Join(ids, ",") != expected {
+		t.Errorf("Expected groups %s, got %v", expected, ids)
+	}
+	if !strings.Contains(buf.String(), "window U  ring C") {
+		t.Errorf("Expected the right rotor's window and ring to be labelled")
+	}
+}
+
+func TestWriteWithTrace(t *testing.T) {
+	m := enigma.NewMachine(enigma.Rotor1(), enigma.Rotor2(), enigma.Rotor3(),
+		enigma.ReflectorB(), 'A', 'A', 'A')
+	p, _ := enigma.NewPlugboard("AZ BY")
+	m.SetPlugboard(p)
+	trace := new(enigma.Trace)
+	m.SetObserver(trace)
+	m.Step('A')
+
+	var buf bytes.Buffer
+	if err := Write(&buf, m, trace); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	ids := parse(t, buf.Bytes())
+	if ids[len(ids)-1] != "path" {
+		t.Errorf("Expected the path to be drawn last, got %v", ids)
+	}
+	if strings.Count(buf.String(), lampColor) != 1 || strings.Count(buf.String(), keyColor) != 1 {
+		t.Errorf("Expected exactly one key and one lamp to be lit")
+	}
+	if !strings.Contains(buf.String(), "AZ BY") {
+		t.Errorf("Expected the plugboard pairs to be labelled")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestWriteError(t *testing.T) {
+	m := enigma.NewMachine(enigma.Rotor1(), enigma.Rotor2(), enigma.Rotor3(),
+		enigma.ReflectorB(), 'A', 'A', 'A')
+	if err := Write(failingWriter{}, m, nil); err != io.ErrClosedPipe {
+		t.Errorf("Expected %s, got %v", io.ErrClosedPipe, err)
+	}
+}
@@ -46,37 +46,178 @@ var englishExpectedFrequency = map[rune]float64{
 	'Z': 0.074,
 }
 
+/*
+	An Analysis counts the letters of a text to compare them with a
+	language. It only uses fixed size arrays, so scoring a text allocates
+	nothing, and it can be Reset and reused.
+*/
 type Analysis struct {
-	characters map[rune]float64
-	total      float64
+	counts   [26]int
+	total    int
+	language *Language
 }
 
-func NewAnalysis() *Analysis {
-	a := Analysis{nil, 0}
+// An Option changes how NewAnalysis sets up an Analysis.
+type Option func(*Analysis)
 
-	a.characters = make(map[rune]float64)
-	for k := range englishExpectedFrequency {
-		a.characters[k] = 0.0
+// WithLanguage compares text with the language instead of English.
+func WithLanguage(l *Language) Option {
+	return func(a *Analysis) {
+		a.language = l
 	}
+}
 
-	return &a
+func NewAnalysis(options ...Option) *Analysis {
+	a := &Analysis{language: english}
+	for _, o := range options {
+		o(a)
+	}
+	return a
 }
 
+// Language returns the language text is compared with.
+func (a *Analysis) Language() *Language {
+	return a.language
+}
+
+// Add counts a letter. Anything other than A-Z is ignored.
 func (a *Analysis) Add(c rune) {
-	a.characters[c] = a.characters[c] + 1
-	a.total++
+	if c >= 'A' && c <= 'Z' {
+		a.counts[c-'A']++
+		a.total++
+	}
+}
+
+// AddText counts every letter of the text.
+func (a *Analysis) AddText(text string) {
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; c >= 'A' && c <= 'Z' {
+			a.counts[c-'A']++
+			a.total++
+		}
+	}
+}
+
+// Remove takes away a letter that was added, e.g. as a window moves on.
+func (a *Analysis) Remove(c rune) {
+	if c >= 'A' && c <= 'Z' && a.counts[c-'A'] > 0 {
+		a.counts[c-'A']--
+		a.total--
+	}
+}
+
+// Reset forgets every letter added, keeping the language.
+func (a *Analysis) Reset() {
+	a.counts = [26]int{}
+	a.total = 0
+}
+
+// Merge adds the letters counted by another analysis.
+func (a *Analysis) Merge(other *Analysis) {
+	for i, count := range other.counts {
+		a.counts[i] += count
+	}
+	a.total += other.total
+}
+
+// Count returns how many times the letter has been added.
+func (a *Analysis) Count(c rune) int {
+	if c < 'A' || c > 'Z' {
+		return 0
+	}
+	return a.counts[c-'A']
+}
+
+// Total returns how many letters have been added.
+func (a *Analysis) Total() int {
+	return a.total
 }
 
 func (a *Analysis) Diff() float64 {
 	diff := 0.0
-	for k := range englishExpectedFrequency {
-		count := a.characters[k]
+	scale := 100 / float64(a.total)
+	for i, count := range a.counts {
 		if count == 0 {
 			continue
 		}
-		actual := (count / a.total) * 100
-		expected := englishExpectedFrequency[k]
-		diff += math.Abs(expected - actual)
+		diff += math.Abs(a.language.percentages[i] - float64(count)*scale)
 	}
 	return diff
 }
+
+/*
+	ChiSquared sums the squared difference between the count of each letter
+	and the count expected in the language, relative to the count expected.
+	It is near 0 for the language and grows with the length of other text.
+	Letters the language never uses are left out.
+*/
+func (a *Analysis) ChiSquared() float64 {
+	chi := 0.0
+	total := float64(a.total)
+	for i, p := range a.language.probabilities {
+		if p == 0 {
+			continue
+		}
+		expected := total * p
+		d := float64(a.counts[i]) - expected
+		chi += d * d / expected
+	}
+	return chi
+}
+
+/*
+	IndexOfCoincidence is the chance that two letters picked from the text
+	are the same, about 0.067 for English, 0.076 for German and 0.038 for
+	random letters.
+*/
+func (a *Analysis) IndexOfCoincidence() float64 {
+	if a.total < 2 {
+		return 0
+	}
+	sum := 0
+	for _, count := range a.counts {
+		sum += count * (count - 1)
+	}
+	return float64(sum) / float64(a.total*(a.total-1))
+}
+
+/*
+	Entropy is the Shannon entropy of the letters in bits, about 4.2 for
+	English and 4.7 for random letters.
+*/
+func (a *Analysis) Entropy() float64 {
+	entropy := 0.0
+	for _, count := range a.counts {
+		if count > 0 {
+			p := float64(count) / float64(a.total)
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+/*
+	ZScore is how many standard deviations the count of the letter is from
+	the count expected in the language, treating each letter of the text as
+	an independent draw. It is 0 for letters the language never uses.
+*/
+func (a *Analysis) ZScore(c rune) float64 {
+	if c < 'A' || c > 'Z' {
+		return 0
+	}
+	p := a.language.probabilities[c-'A']
+	if p == 0 || p == 1 || a.total == 0 {
+		return 0
+	}
+	n := float64(a.total)
+	return (float64(a.counts[c-'A']) - n*p) / math.Sqrt(n*p*(1-p))
+}
+
+// ZScores returns the ZScore of every letter, indexed from A.
+func (a *Analysis) ZScores() [26]float64 {
+	var scores [26]float64
+	for i := range scores {
+		scores[i] = a.ZScore(rune('A' + i))
+	}
+	return scores
+}
@@ -0,0 +1,183 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package frequency
+
+import (
+	"math"
+	"testing"
+)
+
+func analyse(text string) *Analysis {
+	a := NewAnalysis()
+	for _, c := range text {
+		a.Add(c)
+	}
+	return a
+}
+
+func TestIndexOfCoincidence(t *testing.T) {
+	if ic := analyse("AAAA").IndexOfCoincidence(); ic != 1 {
+		t.Errorf("Expected 1, got %f", ic)
+	}
+	if ic := analyse("AABB").IndexOfCoincidence(); ic != 1.0/3 {
+		t.Errorf("Expected 1/3, got %f", ic)
+	}
+	if ic := analyse("A").IndexOfCoincidence(); ic != 0 {
+		t.Errorf("Expected 0 for one letter, got %f", ic)
+	}
+	if ic := analyse(englishText).IndexOfCoincidence(); ic < 0.055 {
+		t.Errorf("Expected English to be near 0.067, got %f", ic)
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	if e := analyse("AAAA").Entropy(); e != 0 {
+		t.Errorf("Expected 0, got %f", e)
+	}
+	if e := analyse("ABCD").Entropy(); e != 2 {
+		t.Errorf("Expected 2, got %f", e)
+	}
+	if e := analyse(englishText).Entropy(); e < 3.8 || e > 4.4 {
+		t.Errorf("Expected English to be near 4.2, got %f", e)
+	}
+}
+
+func TestChiSquared(t *testing.T) {
+	english, random := analyse(englishText), analyse("QZXJKVQZXJKVBPWQZXJKVBPWQZXJKVBPW")
+	if english.ChiSquared() >= random.ChiSquared() {
+		t.Errorf("Expected English to be closer to English, got %f and %f",
+			english.ChiSquared(), random.ChiSquared())
+	}
+}
+
+func TestZScore(t *testing.T) {
+	a := analyse("EEEEEEEEEE")
+	// 10 letters with E expected 12.702% of the time.
+	p := 0.12702
+	expected := (10 - 10*p) / math.Sqrt(10*p*(1-p))
+	if z := a.ZScore('E'); math.Abs(z-expected) > 1e-9 {
+		t.Errorf("Expected %f, got %f", expected, z)
+	}
+	if z := a.ZScores()['T'-'A']; z >= 0 {
+		t.Errorf("Expected a missing T to be below 0, got %f", z)
+	}
+	if z := NewAnalysis().ZScore('E'); z != 0 {
+		t.Errorf("Expected 0 with no letters, got %f", z)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	text := "ABCABD"
+	window := analyse(text[:3])
+	for i := 3; i < len(text); i++ {
+		window.Remove(rune(text[i-3]))
+		window.Add(rune(text[i]))
+	}
+	expected := analyse(text[3:])
+	for _, c := range "ABCD" {
+		if window.Count(c) != expected.Count(c) {
+			t.Errorf("Expected %d of %c, got %d", expected.Count(c), c, window.Count(c))
+		}
+	}
+	if window.Total() != 3 {
+		t.Errorf("Expected 3 letters, got %d", window.Total())
+	}
+
+	window.Remove('Z')
+	if window.Total() != 3 {
+		t.Errorf("Expected removing a letter that isn't there to do nothing")
+	}
+}
+
+func TestLanguageScores(t *testing.T) {
+	a := analyse(englishText)
+	if d := English().Diff(englishText); d != a.Diff() {
+		t.Errorf("Expected %f, got %f", a.Diff(), d)
+	}
+	if c := English().ChiSquared(englishText); c != a.ChiSquared() {
+		t.Errorf("Expected %f, got %f", a.ChiSquared(), c)
+	}
+}
+
+func TestAddIgnoresOtherCharacters(t *testing.T) {
+	a := NewAnalysis()
+	a.AddText("A b!C")
+	a.Add('?')
+	if a.Total() != 2 || a.Count('A') != 1 || a.Count('C') != 1 || a.Count('b') != 0 {
+		t.Errorf("Expected only A and C to be counted, got %v", a.counts)
+	}
+}
+
+func TestResetAndMerge(t *testing.T) {
+	a, b := analyse("AAB"), analyse("BC")
+	a.Merge(b)
+	if a.Count('A') != 2 || a.Count('B') != 2 || a.Count('C') != 1 || a.Total() != 5 {
+		t.Errorf("Expected AAB and BC merged, got %v", a.counts)
+	}
+
+	a.Reset()
+	if a.Total() != 0 || a.counts != [26]int{} {
+		t.Errorf("Expected an empty analysis, got %v", a.counts)
+	}
+}
+
+// Scoring a candidate plaintext the way crack does, once per configuration.
+func BenchmarkScore(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		a := NewAnalysis()
+		a.AddText(englishText)
+		a.Diff()
+	}
+}
+
+// The same without allocating an Analysis.
+func BenchmarkLanguageDiff(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		English().Diff(englishText)
+	}
+}
+
+func BenchmarkDiff(b *testing.B) {
+	a := analyse(englishText)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.Diff()
+	}
+}
+
+func BenchmarkChiSquared(b *testing.B) {
+	a := analyse(englishText)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.ChiSquared()
+	}
+}
+
+func BenchmarkSlidingWindow(b *testing.B) {
+	a := analyse(englishText[:50])
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := i % (len(englishText) - 50)
+		a.Remove(rune(englishText[j]))
+		a.Add(rune(englishText[j+50]))
+		a.IndexOfCoincidence()
+	}
+}
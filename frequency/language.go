@@ -0,0 +1,311 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package frequency
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+	A Language holds the percentage of the letters in its text that are
+	each of A-Z. Accented letters are counted as the letters they are
+	written with on an Enigma, e.g. Ä as AE.
+*/
+type Language struct {
+	Name        string
+	Frequencies map[rune]float64
+
+	// The same, indexed from A, and as fractions of 1.
+	percentages, probabilities [26]float64
+}
+
+/*
+	NewLanguage makes a language from the percentage of each letter. The
+	frequencies must not be changed afterwards.
+*/
+func NewLanguage(name string, frequencies map[rune]float64) *Language {
+	l := &Language{Name: name, Frequencies: frequencies}
+	for i := range l.percentages {
+		l.percentages[i] = frequencies[rune('A'+i)]
+		l.probabilities[i] = l.percentages[i] / 100
+	}
+	return l
+}
+
+/*
+	The German, Spanish and Italian tables are the commonly published ones,
+	with accented letters folded in and scaled back to 100.
+*/
+var english = NewLanguage("english", englishExpectedFrequency)
+
+var languages = map[string]*Language{
+	"english":  english,
+	"german":   NewLanguage("german", germanExpectedFrequency),
+	"spanish":  NewLanguage("spanish", spanishExpectedFrequency),
+	"italian":  NewLanguage("italian", italianExpectedFrequency),
+	"german-x": NewLanguage("german-x", germanXExpectedFrequency),
+}
+
+/*
+	Diff is Analysis.Diff of the text, for scoring many texts without
+	allocating an Analysis for each.
+*/
+func (l *Language) Diff(text string) float64 {
+	a := Analysis{language: l}
+	a.AddText(text)
+	return a.Diff()
+}
+
+// ChiSquared is Analysis.ChiSquared of the text, see Diff.
+func (l *Language) ChiSquared(text string) float64 {
+	a := Analysis{language: l}
+	a.AddText(text)
+	return a.ChiSquared()
+}
+
+/*
+	IndexOfCoincidence is the chance that two letters of text in the
+	language are the same, the sum of the squares of the probabilities of
+	the letters.
+*/
+func (l *Language) IndexOfCoincidence() float64 {
+	sum, squares := 0.0, 0.0
+	for _, p := range l.probabilities {
+		sum += p
+		squares += p * p
+	}
+	if sum == 0 {
+		return 0
+	}
+	return squares / (sum * sum)
+}
+
+// English returns the language NewAnalysis uses by default.
+func English() *Language {
+	return english
+}
+
+// LanguageNames returns the names LanguageByName accepts.
+func LanguageNames() []string {
+	var names []string
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LanguageByName returns one of the languages named by LanguageNames.
+func LanguageByName(name string) (*Language, error) {
+	if l, ok := languages[strings.ToLower(name)]; ok {
+		return l, nil
+	}
+	return nil, fmt.Errorf("unknown language %q, expected one of %s", name, strings.Join(LanguageNames(), ", "))
+}
+
+/*
+	ReadLanguage reads a language from lines of a letter and how common it
+	is, e.g. "E 12.7". The numbers can be counts or percentages, they are
+	scaled to add up to 100. Letters that aren't given are never expected.
+*/
+func ReadLanguage(name string, r io.Reader) (*Language, error) {
+	frequencies := make(map[rune]float64)
+	for c := 'A'; c <= 'Z'; c++ {
+		frequencies[c] = 0
+	}
+
+	total := 0.0
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		letter := strings.ToUpper(fields[0])
+		if len(fields) != 2 || len(letter) != 1 || letter[0] < 'A' || letter[0] > 'Z' {
+			return nil, fmt.Errorf("line %d: expected a letter and a number", line)
+		}
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("line %d: invalid number %q", line, fields[1])
+		}
+		frequencies[rune(letter[0])] += v
+		total += v
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("no letter frequencies found")
+	}
+	for c, v := range frequencies {
+		frequencies[c] = 100 * v / total
+	}
+	return NewLanguage(name, frequencies), nil
+}
+
+// LoadLanguage reads a language from a file, named after the file.
+func LoadLanguage(path string) (*Language, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadLanguage(path, f)
+}
+
+/*
+	LookupLanguage returns the language with the name, or else loads it from
+	the file with that path.
+*/
+func LookupLanguage(nameOrPath string) (*Language, error) {
+	if l, ok := languages[strings.ToLower(nameOrPath)]; ok {
+		return l, nil
+	}
+	if _, err := os.Stat(nameOrPath); err != nil {
+		return nil, fmt.Errorf("unknown language %q, expected one of %s or a file",
+			nameOrPath, strings.Join(LanguageNames(), ", "))
+	}
+	return LoadLanguage(nameOrPath)
+}
+
+/*
+	German with an X between words, as it was written for Enigma traffic.
+	This is only an estimate, not counted from real messages: the German
+	table scaled down with one X added for every six letters, the average
+	length of a German word.
+*/
+var germanXExpectedFrequency = map[rune]float64{
+	'A': 5.942,
+	'B': 1.580,
+	'C': 2.289,
+	'D': 4.252,
+	'E': 15.423,
+	'F': 1.387,
+	'G': 2.521,
+	'H': 3.834,
+	'I': 5.487,
+	'J': 0.224,
+	'K': 1.187,
+	'L': 2.879,
+	'M': 2.123,
+	'N': 8.189,
+	'O': 2.544,
+	'P': 0.561,
+	'Q': 0.015,
+	'R': 5.866,
+	'S': 6.604,
+	'T': 5.155,
+	'U': 4.323,
+	'V': 0.709,
+	'W': 1.609,
+	'X': 14.314,
+	'Y': 0.033,
+	'Z': 0.950,
+}
+
+var germanExpectedFrequency = map[rune]float64{
+	'A': 6.933,
+	'B': 1.843,
+	'C': 2.670,
+	'D': 4.961,
+	'E': 17.994,
+	'F': 1.618,
+	'G': 2.941,
+	'H': 4.473,
+	'I': 6.401,
+	'J': 0.262,
+	'K': 1.385,
+	'L': 3.359,
+	'M': 2.476,
+	'N': 9.554,
+	'O': 2.968,
+	'P': 0.655,
+	'Q': 0.018,
+	'R': 6.844,
+	'S': 7.705,
+	'T': 6.014,
+	'U': 5.044,
+	'V': 0.827,
+	'W': 1.877,
+	'X': 0.033,
+	'Y': 0.038,
+	'Z': 1.108,
+}
+
+var spanishExpectedFrequency = map[rune]float64{
+	'A': 12.027,
+	'B': 2.215,
+	'C': 4.019,
+	'D': 5.010,
+	'E': 12.614,
+	'F': 0.692,
+	'G': 1.768,
+	'H': 0.703,
+	'I': 6.972,
+	'J': 0.493,
+	'K': 0.011,
+	'L': 4.967,
+	'M': 3.157,
+	'N': 7.023,
+	'O': 9.510,
+	'P': 2.510,
+	'Q': 0.877,
+	'R': 6.871,
+	'S': 7.977,
+	'T': 4.632,
+	'U': 3.107,
+	'V': 1.138,
+	'W': 0.017,
+	'X': 0.215,
+	'Y': 1.008,
+	'Z': 0.467,
+}
+
+var italianExpectedFrequency = map[rune]float64{
+	'A': 12.379,
+	'B': 0.927,
+	'C': 4.501,
+	'D': 3.736,
+	'E': 12.054,
+	'F': 1.153,
+	'G': 1.644,
+	'H': 0.636,
+	'I': 10.172,
+	'J': 0.011,
+	'K': 0.009,
+	'L': 6.510,
+	'M': 2.512,
+	'N': 6.883,
+	'O': 9.833,
+	'P': 3.056,
+	'Q': 0.505,
+	'R': 6.367,
+	'S': 4.981,
+	'T': 5.623,
+	'U': 3.177,
+	'V': 2.097,
+	'W': 0.033,
+	'X': 0.003,
+	'Y': 0.020,
+	'Z': 1.181,
+}
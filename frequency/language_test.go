@@ -0,0 +1,122 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package frequency
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const germanText = "DIEVERBINDUNGZURDRITTENARMEEISTUNTERBROCHENWIRERWARTENWEITEREBEFEHLE" +
+	"UNDBITTENUMDRINGENDENNACHSCHUBANMUNITIONUNDVERPFLEGUNGFUERDIETRUPPEN"
+
+const englishText = "THECONNECTIONTOTHETHIRDARMYHASBEENBROKENWEAREWAITINGFORFURTHERORDERS" +
+	"ANDASKURGENTLYFORMORESUPPLIESOFAMMUNITIONANDFOODFORTHETROOPS"
+
+func diff(text string, options ...Option) float64 {
+	a := NewAnalysis(options...)
+	for _, c := range text {
+		a.Add(c)
+	}
+	return a.Diff()
+}
+
+func TestLanguages(t *testing.T) {
+	for _, name := range LanguageNames() {
+		l, err := LanguageByName(name)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		total := 0.0
+		for _, v := range l.Frequencies {
+			total += v
+		}
+		if len(l.Frequencies) != 26 || math.Abs(total-100) > 0.1 {
+			t.Errorf("Expected 26 letters adding up to 100 for %s, got %d adding up to %f",
+				name, len(l.Frequencies), total)
+		}
+	}
+	if _, err := LanguageByName("klingon"); err == nil {
+		t.Errorf("Expected an error for an unknown language")
+	}
+}
+
+func TestLanguageIndexOfCoincidence(t *testing.T) {
+	english, _ := LanguageByName("english")
+	german, _ := LanguageByName("german")
+	if ic := english.IndexOfCoincidence(); math.Abs(ic-0.0655) > 0.002 {
+		t.Errorf("Expected about 0.0655 for English, got %f", ic)
+	}
+	if german.IndexOfCoincidence() <= english.IndexOfCoincidence() {
+		t.Errorf("Expected German letters to repeat more than English ones")
+	}
+	if ic := NewLanguage("none", nil).IndexOfCoincidence(); ic != 0 {
+		t.Errorf("Expected 0, got %f", ic)
+	}
+}
+
+func TestWithLanguage(t *testing.T) {
+	german, _ := LanguageByName("german")
+	if NewAnalysis().Language() != English() {
+		t.Errorf("Expected English by default")
+	}
+	if diff(germanText, WithLanguage(german)) >= diff(germanText) {
+		t.Errorf("Expected German text to be closer to German than English")
+	}
+	if diff(englishText) >= diff(englishText, WithLanguage(german)) {
+		t.Errorf("Expected English text to be closer to English than German")
+	}
+}
+
+func TestReadLanguage(t *testing.T) {
+	l, err := ReadLanguage("test", strings.NewReader("# counts\nA 3\ne 1\n\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if l.Frequencies['A'] != 75 || l.Frequencies['E'] != 25 || l.Frequencies['Z'] != 0 {
+		t.Errorf("Expected A 75 and E 25, got %v", l.Frequencies)
+	}
+
+	for _, invalid := range []string{"", "A", "AB 1", "1 1", "A x", "A -1"} {
+		if _, err := ReadLanguage("test", strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected an error reading %q", invalid)
+		}
+	}
+}
+
+func TestLookupLanguage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "language")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "latin.txt")
+	ioutil.WriteFile(path, []byte("E 12\nI 11\n"), 0644)
+
+	if l, err := LookupLanguage("German"); err != nil || l.Name != "german" {
+		t.Errorf("Expected german, got %v %v", l, err)
+	}
+	if l, err := LookupLanguage(path); err != nil || l.Frequencies['I'] == 0 {
+		t.Errorf("Expected the language from %s, got %v %v", path, l, err)
+	}
+	if _, err := LookupLanguage(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Expected an error for an unknown language")
+	}
+}
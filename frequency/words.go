@@ -0,0 +1,32 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package frequency
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed data/english-words.txt
+var englishWords string
+
+/*
+	EnglishWords returns the thousand most common English words of at least
+	three letters in Newton's Opticks, in capitals, most common first.
+*/
+func EnglishWords() []string {
+	return strings.Fields(englishWords)
+}
@@ -0,0 +1,171 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package enigma
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+var rotorNames = []string{"I", "II", "III", "IV", "V", "VI", "VII", "VIII"}
+var rotorConstructors = []func() *Rotor{Rotor1, Rotor2, Rotor3, Rotor4, Rotor5,
+	Rotor6, Rotor7, Rotor8}
+
+var reflectorNames = []string{"A", "B", "C"}
+var reflectorConstructors = []func() *Rotor{ReflectorA, ReflectorB, ReflectorC}
+
+// RotorByName returns a new rotor given its roman numeral, "I" to "VIII".
+func RotorByName(name string) (*Rotor, error) {
+	for i, n := range rotorNames {
+		if strings.EqualFold(n, name) {
+			return rotorConstructors[i](), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown rotor %q", name)
+}
+
+// ReflectorByName returns a new reflector given its letter, "A", "B" or "C".
+func ReflectorByName(name string) (*Rotor, error) {
+	for i, n := range reflectorNames {
+		if strings.EqualFold(n, name) {
+			return reflectorConstructors[i](), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown reflector %q", name)
+}
+
+/*
+	A Key holds everything needed to set up a machine, written the way a key
+	sheet would. Empty rings or positions mean AAA.
+*/
+type Key struct {
+	Reflector string   `json:"reflector"`
+	Rotors    []string `json:"rotors"` // Left to right, e.g. I, II, III.
+	Rings     string   `json:"rings,omitempty"`
+	Positions string   `json:"positions,omitempty"`
+	Plugboard string   `json:"plugboard,omitempty"` // Pairs, e.g. "AB CD".
+}
+
+// Validate returns an error describing the first problem with the key.
+func (k *Key) Validate() error {
+	_, err := k.NewMachine()
+	return err
+}
+
+// NewMachine returns a machine set up with the key.
+func (k *Key) NewMachine() (*Machine, error) {
+	reflector, err := ReflectorByName(k.Reflector)
+	if err != nil {
+		return nil, err
+	}
+	if len(k.Rotors) != 3 {
+		return nil, fmt.Errorf("expected 3 rotors, got %d", len(k.Rotors))
+	}
+	var rotors [3]*Rotor
+	for i, name := range k.Rotors {
+		for _, other := range k.Rotors[:i] {
+			if strings.EqualFold(name, other) {
+				return nil, fmt.Errorf("rotor %s used more than once", name)
+			}
+		}
+		if rotors[i], err = RotorByName(name); err != nil {
+			return nil, err
+		}
+	}
+	rings, err := threeLetters("rings", k.Rings)
+	if err != nil {
+		return nil, err
+	}
+	positions, err := threeLetters("positions", k.Positions)
+	if err != nil {
+		return nil, err
+	}
+	plugboard, err := NewPlugboard(k.Plugboard)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewMachine(rotors[0], rotors[1], rotors[2], reflector,
+		positions[0], positions[1], positions[2])
+	m.SetRings(rings[0], rings[1], rings[2])
+	if k.Plugboard != "" {
+		m.SetPlugboard(plugboard)
+	}
+	return m, nil
+}
+
+func threeLetters(name, s string) ([]rune, error) {
+	if s == "" {
+		return []rune("AAA"), nil
+	}
+	letters := []rune(strings.ToUpper(s))
+	if len(letters) != 3 {
+		return nil, fmt.Errorf("expected 3 letters for %s, got %q", name, s)
+	}
+	for _, l := range letters {
+		if !isLetter(l) {
+			return nil, fmt.Errorf("expected 3 letters for %s, got %q", name, s)
+		}
+	}
+	return letters, nil
+}
+
+func (k Key) String() string {
+	s := fmt.Sprintf("%s %s %s %s", k.Reflector, strings.Join(k.Rotors, "-"),
+		orAAA(k.Rings), orAAA(k.Positions))
+	if k.Plugboard != "" {
+		s += " " + k.Plugboard
+	}
+	return s
+}
+
+func orAAA(s string) string {
+	if s == "" {
+		return "AAA"
+	}
+	return s
+}
+
+/*
+	RandomKey returns a key like those issued to the army: reflector B, 3
+	different rotors from I-V, random rings and positions and 10 plug pairs.
+*/
+func RandomKey(r *rand.Rand) Key {
+	k := Key{Reflector: "B"}
+	for _, i := range r.Perm(5)[:3] {
+		k.Rotors = append(k.Rotors, rotorNames[i])
+	}
+	k.Rings = randomLetters(r, 3)
+	k.Positions = randomLetters(r, 3)
+
+	letters := r.Perm(26)
+	pairs := make([]string, 10)
+	for i := range pairs {
+		pairs[i] = string([]rune{LETTERS[letters[2*i]], LETTERS[letters[2*i+1]]})
+	}
+	p, _ := NewPlugboard(strings.Join(pairs, " "))
+	k.Plugboard = p.String()
+	return k
+}
+
+func randomLetters(r *rand.Rand, n int) string {
+	letters := make([]rune, n)
+	for i := range letters {
+		letters[i] = LETTERS[r.Intn(26)]
+	}
+	return string(letters)
+}
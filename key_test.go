@@ -0,0 +1,71 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package enigma
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestKeyNewMachine(t *testing.T) {
+	k := Key{Reflector: "B", Rotors: []string{"I", "II", "III"}, Rings: "BBB"}
+	m, err := k.NewMachine()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if actual := m.Encrypt("AAAAA"); actual != "EWTYX" {
+		t.Errorf("Expected EWTYX, got %s", actual)
+	}
+	if k.String() != "B I-II-III BBB AAA" {
+		t.Errorf("Expected B I-II-III BBB AAA, got %s", k)
+	}
+}
+
+func TestKeyValidate(t *testing.T) {
+	invalid := []Key{
+		{Reflector: "D", Rotors: []string{"I", "II", "III"}},
+		{Reflector: "B", Rotors: []string{"I", "II"}},
+		{Reflector: "B", Rotors: []string{"I", "II", "IX"}},
+		{Reflector: "B", Rotors: []string{"I", "II", "I"}},
+		{Reflector: "B", Rotors: []string{"I", "II", "III"}, Rings: "AB"},
+		{Reflector: "B", Rotors: []string{"I", "II", "III"}, Positions: "A1C"},
+		{Reflector: "B", Rotors: []string{"I", "II", "III"}, Plugboard: "AB BC"},
+	}
+	for _, k := range invalid {
+		if k.Validate() == nil {
+			t.Errorf("Expected %s to be invalid", k)
+		}
+	}
+
+	valid := Key{Reflector: "c", Rotors: []string{"viii", "II", "V"}, Positions: "xyz",
+		Plugboard: "AB CD"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestRandomKey(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		k := RandomKey(r)
+		if err := k.Validate(); err != nil {
+			t.Errorf("Expected a valid key, got %s: %s", k, err)
+		}
+		if len(k.Plugboard) != 29 {
+			t.Errorf("Expected 10 pairs, got %s", k.Plugboard)
+		}
+	}
+}
@@ -29,6 +29,16 @@ type Machine struct {
 	// The current positions of the 3 Rotors, stored as integers in the
 	// range 0-25.
 	p1, p2, p3 int32
+
+	// The ring settings of the 3 Rotors, also in the range 0-25. Zero, the
+	// letter A, leaves the wiring where it is.
+	g1, g2, g3 int32
+
+	// Optional, nil when no plugs are in use.
+	plugboard *Plugboard
+
+	// Optional, nil unless someone is watching the signal path.
+	observer Observer
 }
 
 var freeList = make(chan *Machine, 1000000)
@@ -43,6 +53,8 @@ func FreeMachine(m *Machine) {
 	m.r1, m.r2, m.r3, m.reflector = nil, nil, nil, nil
 	m.s1, m.s2, m.s3 = 'A', 'A', 'A'
 	m.p1, m.p2, m.p3 = 0, 0, 0
+	m.g1, m.g2, m.g3 = 0, 0, 0
+	m.plugboard, m.observer = nil, nil
 	freeList <- m
 }
 
@@ -54,7 +66,7 @@ func NewMachine(r1, r2, r3, reflector *Rotor, s1, s2, s3 rune) *Machine {
 	default:
 		populateMachineFreeList()
 		m = new(Machine)
-		m.init(r1, r2, r3, reflector, s1, s2, s2)
+		m.init(r1, r2, r3, reflector, s1, s2, s3)
 	}
 	return m
 }
@@ -72,38 +84,167 @@ func (m *Machine) init(r1, r2, r3, reflector *Rotor, s1, s2, s3 rune) {
 	Step() will move the routers and output the resulting letter.
 */
 func (m *Machine) Step(input rune) rune {
+	if m.observer != nil {
+		return m.observedStep(input)
+	}
 	m.moveRotors()
-	x := getOutputIndex(m.r3, m.p3, input-'A', false)
-	x = getOutputIndex(m.r2, m.p2, x, false)
-	x = getOutputIndex(m.r1, m.p1, x, false)
+	return LETTERS[m.encode(input-'A')]
+}
+
+/*
+	SetRings sets the ring settings (Ringstellung) of the 3 Rotors. The rings
+	turn the wiring relative to the letters in the windows, while the
+	turnover points stay with the letters.
+*/
+func (m *Machine) SetRings(g1, g2, g3 rune) {
+	m.g1 = g1 - 'A'
+	m.g2 = g2 - 'A'
+	m.g3 = g3 - 'A'
+}
+
+// Rings returns the ring settings of the 3 Rotors as letters.
+func (m *Machine) Rings() string {
+	return string([]rune{LETTERS[m.g1], LETTERS[m.g2], LETTERS[m.g3]})
+}
+
+// Rotors returns the left, middle and right rotors.
+func (m *Machine) Rotors() (r1, r2, r3 *Rotor) {
+	return m.r1, m.r2, m.r3
+}
+
+func (m *Machine) Reflector() *Rotor {
+	return m.reflector
+}
+
+// SetPlugboard sets the plugs to use, nil removes all of them.
+func (m *Machine) SetPlugboard(p *Plugboard) {
+	m.plugboard = p
+}
+
+func (m *Machine) Plugboard() *Plugboard {
+	return m.plugboard
+}
+
+// SetObserver sets an observer to be told about every Step, or nil for none.
+func (m *Machine) SetObserver(o Observer) {
+	m.observer = o
+}
+
+// Sends a letter index through the rotors and back without moving them.
+func (m *Machine) encode(x int32) int32 {
+	o1, o2, o3 := m.offsets()
+	if m.plugboard != nil {
+		x = m.plugboard.mapping[x]
+	}
+	x = getOutputIndex(m.r3, o3, x, false)
+	x = getOutputIndex(m.r2, o2, x, false)
+	x = getOutputIndex(m.r1, o1, x, false)
 	x = getOutputIndex(m.reflector, 0, x, false)
-	x = getOutputIndex(m.r1, m.p1, x, true)
-	x = getOutputIndex(m.r2, m.p2, x, true)
-	x = getOutputIndex(m.r3, m.p3, x, true)
+	x = getOutputIndex(m.r1, o1, x, true)
+	x = getOutputIndex(m.r2, o2, x, true)
+	x = getOutputIndex(m.r3, o3, x, true)
+	if m.plugboard != nil {
+		x = m.plugboard.mapping[x]
+	}
+	return x
+}
+
+// The same as Step, but tells the observer about each part of the path.
+func (m *Machine) observedStep(input rune) rune {
+	before := m.Positions()
+	m.moveRotors()
+	m.observer.Stepped(before, m.Positions())
+
+	o1, o2, o3 := m.offsets()
+	x := input - 'A'
+	pass := func(c Component, out int32) {
+		m.observer.Passed(c, LETTERS[x], LETTERS[out])
+		x = out
+	}
+	if m.plugboard != nil {
+		pass(PlugboardComponent, m.plugboard.mapping[x])
+	}
+	pass(RightRotorComponent, getOutputIndex(m.r3, o3, x, false))
+	pass(MiddleRotorComponent, getOutputIndex(m.r2, o2, x, false))
+	pass(LeftRotorComponent, getOutputIndex(m.r1, o1, x, false))
+	pass(ReflectorComponent, getOutputIndex(m.reflector, 0, x, false))
+	pass(LeftRotorComponent, getOutputIndex(m.r1, o1, x, true))
+	pass(MiddleRotorComponent, getOutputIndex(m.r2, o2, x, true))
+	pass(RightRotorComponent, getOutputIndex(m.r3, o3, x, true))
+	if m.plugboard != nil {
+		pass(PlugboardComponent, m.plugboard.mapping[x])
+	}
 	return LETTERS[x]
 }
 
-func (m *Machine) moveRotors() {
-	m.p3 = (m.p3 + 1) % 26
-	if m.r3.Turnover(m.p3) {
-		m.p2 = (m.p2 + 1) % 26
-		if m.r2.Turnover(m.p2) {
-			m.p1 = (m.p1 + 1) % 26
+/*
+	Wiring returns how the component currently connects the contacts on the
+	side facing the keyboard to the contacts on the side facing the reflector,
+	for the rotors at their current positions.
+*/
+func (m *Machine) Wiring(c Component) Permutation {
+	o1, o2, o3 := m.offsets()
+	var p Permutation
+	for i := range p {
+		x := int32(i)
+		switch c {
+		case PlugboardComponent:
+			if m.plugboard != nil {
+				x = m.plugboard.mapping[x]
+			}
+		case RightRotorComponent:
+			x = getOutputIndex(m.r3, o3, x, false)
+		case MiddleRotorComponent:
+			x = getOutputIndex(m.r2, o2, x, false)
+		case LeftRotorComponent:
+			x = getOutputIndex(m.r1, o1, x, false)
+		case ReflectorComponent:
+			x = getOutputIndex(m.reflector, 0, x, false)
 		}
+		p[i] = LETTERS[x]
 	}
+	return p
+}
 
-	// Handles double-stepping case
-	if m.r3.Turnover(m.p3-1) && m.r2.Turnover(m.p2+1) {
+// The offset of each rotor's wiring, its position less its ring setting.
+func (m *Machine) offsets() (o1, o2, o3 int32) {
+	return (m.p1 - m.g1 + 26) % 26, (m.p2 - m.g2 + 26) % 26, (m.p3 - m.g3 + 26) % 26
+}
+
+func (m *Machine) moveRotors() {
+	if m.r2.atNotch(m.p2) {
+		// Handles double-stepping case, the pawl that pushes the left rotor
+		// also pushes the middle rotor.
+		m.p1 = (m.p1 + 1) % 26
+		m.p2 = (m.p2 + 1) % 26
+	} else if m.r3.atNotch(m.p3) {
 		m.p2 = (m.p2 + 1) % 26
-		if m.r2.Turnover(m.p2) {
-			m.p1 = (m.p1 + 1) % 26
-		}
 	}
+	m.p3 = (m.p3 + 1) % 26
+}
+
+// Positions returns the letters currently showing in the rotor windows.
+func (m *Machine) Positions() string {
+	return string([]rune{LETTERS[m.p1], LETTERS[m.p2], LETTERS[m.p3]})
+}
+
+// SetPositions turns the rotors so the given letters show in the windows.
+func (m *Machine) SetPositions(p1, p2, p3 rune) {
+	m.p1 = p1 - 'A'
+	m.p2 = p2 - 'A'
+	m.p3 = p3 - 'A'
 }
 
 func (m *Machine) String() string {
-	return fmt.Sprintf("KEY: %c%c%c\nROTORS: %s, %s, %s\nREFLECTOR: %s",
+	s := fmt.Sprintf("KEY: %c%c%c\nROTORS: %s, %s, %s\nREFLECTOR: %s",
 		m.s1, m.s2, m.s3, m.r1, m.r2, m.r3, m.reflector)
+	if m.g1 != 0 || m.g2 != 0 || m.g3 != 0 {
+		s += "\nRINGS: " + m.Rings()
+	}
+	if m.plugboard != nil {
+		s += "\nPLUGBOARD: " + m.plugboard.String()
+	}
+	return s
 }
 
 func getOutputIndex(r *Rotor, offset, inputIndex int32, reverse bool) int32 {
@@ -98,3 +98,38 @@ func TestStep(t *testing.T) {
 	assertEqualsRune(t, 'Y', m.Step('L'))
 	assertEqualsRune(t, 'W', m.Step('L'))
 }
+
+func TestMoveRotorDoubleStep(t *testing.T) {
+	// The middle rotor starts at its notch, so it steps along with the left.
+	m := NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'E', 'A')
+	m.moveRotors()
+	assertRotorPositions(t, 1, 5, 1, m)
+
+	// Rotor 5 turns over from Z to A.
+	m = NewMachine(Rotor1(), Rotor2(), Rotor5(), ReflectorB(), 'A', 'A', 'Z')
+	m.moveRotors()
+	assertRotorPositions(t, 0, 1, 0, m)
+}
+
+func TestStepWithRings(t *testing.T) {
+	m := NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'A', 'A')
+	if actual := m.Encrypt("AAAAA"); actual != "BDZGO" {
+		t.Errorf("Expected BDZGO, got %s", actual)
+	}
+
+	m = NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'A', 'A')
+	m.SetRings('B', 'B', 'B')
+	if actual := m.Encrypt("AAAAA"); actual != "EWTYX" {
+		t.Errorf("Expected EWTYX, got %s", actual)
+	}
+	if m.Rings() != "BBB" {
+		t.Errorf("Expected BBB, got %s", m.Rings())
+	}
+}
+
+func TestWiring(t *testing.T) {
+	m := NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'A', 'B')
+	assertEqualsRune(t, 'C', m.Wiring(RightRotorComponent)['A'-'A'])
+	assertEqualsRune(t, 'S', m.Wiring(ReflectorComponent)['F'-'A'])
+	assertEqualsRune(t, 'Q', m.Wiring(PlugboardComponent)['Q'-'A'])
+}
@@ -0,0 +1,65 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package enigma
+
+import (
+	"bytes"
+	"unicode"
+)
+
+/*
+	A Codec converts between ordinary readable text and the plain A-Z text an
+	operator would actually type into the machine.
+*/
+type Codec interface {
+	// Encode turns readable text into plaintext that is ready to encrypt.
+	Encode(text string) string
+
+	// Decode turns decrypted plaintext back into readable text.
+	Decode(plaintext string) string
+}
+
+/*
+	Encrypt runs every letter of message through the machine and returns the
+	result. Lower case letters are treated as upper case and anything else is
+	dropped, since the keyboard has no keys for it.
+*/
+func (m *Machine) Encrypt(message string) string {
+	var buf bytes.Buffer
+	for _, c := range message {
+		c = unicode.ToUpper(c)
+		if c < 'A' || c > 'Z' {
+			continue
+		}
+		buf.WriteRune(m.Step(c))
+	}
+	return buf.String()
+}
+
+// Decrypt is identical to Encrypt because the machine is reciprocal.
+func (m *Machine) Decrypt(message string) string {
+	return m.Encrypt(message)
+}
+
+// EncryptText encodes text with the codec and then encrypts it.
+func (m *Machine) EncryptText(text string, c Codec) string {
+	return m.Encrypt(c.Encode(text))
+}
+
+// DecryptText decrypts message and decodes the result with the codec.
+func (m *Machine) DecryptText(message string, c Codec) string {
+	return c.Decode(m.Decrypt(message))
+}
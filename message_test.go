@@ -0,0 +1,53 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package enigma
+
+import (
+	"strings"
+	"testing"
+)
+
+type upperCodec struct{}
+
+func (upperCodec) Encode(text string) string {
+	return strings.Replace(strings.ToUpper(text), " ", "X", -1)
+}
+
+func (upperCodec) Decode(plaintext string) string {
+	return strings.Replace(plaintext, "X", " ", -1)
+}
+
+func TestEncrypt(t *testing.T) {
+	m := NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'A', 'A')
+	if actual := m.Encrypt("apple, pie"); actual != "BHSDRUPY" {
+		t.Errorf("Expected BHSDRUPY, got %s", actual)
+	}
+
+	m = NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'A', 'A')
+	if actual := m.Decrypt("BHSDRUPY"); actual != "APPLEPIE" {
+		t.Errorf("Expected APPLEPIE, got %s", actual)
+	}
+}
+
+func TestEncryptText(t *testing.T) {
+	m := NewMachine(Rotor3(), Rotor1(), Rotor2(), ReflectorB(), 'V', 'P', 'C')
+	encrypted := m.EncryptText("attack at dawn", upperCodec{})
+
+	m = NewMachine(Rotor3(), Rotor1(), Rotor2(), ReflectorB(), 'V', 'P', 'C')
+	if actual := m.DecryptText(encrypted, upperCodec{}); actual != "ATTACK AT DAWN" {
+		t.Errorf("Expected ATTACK AT DAWN, got %s", actual)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package ngram
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+/*
+	The binary format is the magic bytes, the length of the runs and then
+	the log probability of each run, in the order of Model's index, as a
+	little endian int16 in thousandths. That keeps a model of runs of four
+	letters under a megabyte.
+*/
+var magic = []byte("NGRAM1")
+
+const scale = 1000
+
+// WriteBinary writes the model in the binary format ReadBinary reads.
+func (m *Model) WriteBinary(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	buf.Write(magic)
+	buf.WriteByte(byte(m.N))
+	values := make([]int16, len(m.logProbabilities))
+	for i, p := range m.logProbabilities {
+		values[i] = int16(math.Max(math.Round(p*scale), math.MinInt16))
+	}
+	if err := binary.Write(buf, binary.LittleEndian, values); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// ReadBinary reads a model written by WriteBinary.
+func ReadBinary(r io.Reader) (*Model, error) {
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:len(magic)], magic) {
+		return nil, errors.New("not a binary n-gram model")
+	}
+	n := int(header[len(magic)])
+	if n < 1 || n > MaxN {
+		return nil, fmt.Errorf("runs of %d letters aren't supported", n)
+	}
+
+	values := make([]int16, size(n))
+	if err := binary.Read(bufio.NewReader(r), binary.LittleEndian, values); err != nil {
+		return nil, err
+	}
+	m := &Model{N: n, logProbabilities: make([]float64, len(values))}
+	for i, v := range values {
+		m.logProbabilities[i] = float64(v) / scale
+	}
+	return m, nil
+}
+
+// Load reads a model from a file in either the binary or text format.
+func Load(path string) (*Model, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	if start, _ := r.Peek(len(magic)); bytes.Equal(start, magic) {
+		return ReadBinary(r)
+	}
+	return ReadText(r)
+}
@@ -0,0 +1,197 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+/*
+	Package ngram scores text by how likely its runs of letters are in a
+	language.
+*/
+package ngram
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MaxN is the longest run of letters a model can hold.
+const MaxN = 4
+
+/*
+	A Model holds the log10 probability of every run of N letters, with
+	runs that were never seen given a tenth of a single occurrence.
+*/
+type Model struct {
+	N int
+
+	// Indexed by the letters of the run read as a number in base 26.
+	logProbabilities []float64
+}
+
+/*
+	ReadText reads a model from lines of a run of letters and the number of
+	times it was seen, e.g. "THE 9825". Every run must be the same length.
+*/
+func ReadText(r io.Reader) (*Model, error) {
+	counts := make(map[string]float64)
+	n := 0
+	total := 0.0
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a run of letters and a count", line)
+		}
+		gram := strings.ToUpper(fields[0])
+		if n == 0 {
+			if n = len(gram); n > MaxN {
+				return nil, fmt.Errorf("line %d: runs of more than %d letters aren't supported", line, MaxN)
+			}
+		}
+		if len(gram) != n || strings.IndexFunc(gram, notLetter) >= 0 {
+			return nil, fmt.Errorf("line %d: expected %d letters, got %q", line, n, fields[0])
+		}
+		count, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("line %d: invalid count %q", line, fields[1])
+		}
+		counts[gram] += count
+		total += count
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("no counts found")
+	}
+
+	dense := make([]float64, size(n))
+	for gram, count := range counts {
+		dense[index(gram)] = count
+	}
+	return newModel(n, dense, total), nil
+}
+
+// The number of different runs of n letters.
+func size(n int) int {
+	return int(math.Pow(26, float64(n)))
+}
+
+// Makes a model from the count of each run, indexed as in Model.
+func newModel(n int, counts []float64, total float64) *Model {
+	m := &Model{N: n, logProbabilities: make([]float64, len(counts))}
+	floor := math.Log10(0.1 / total)
+	for i, count := range counts {
+		if count > 0 {
+			m.logProbabilities[i] = math.Log10(count / total)
+		} else {
+			m.logProbabilities[i] = floor
+		}
+	}
+	return m
+}
+
+func notLetter(r rune) bool {
+	return r < 'A' || r > 'Z'
+}
+
+func index(gram string) int {
+	i := 0
+	for j := 0; j < len(gram); j++ {
+		i = i*26 + int(gram[j]-'A')
+	}
+	return i
+}
+
+// LogProbability returns the log10 probability of a run of N letters.
+func (m *Model) LogProbability(gram string) float64 {
+	return m.logProbabilities[index(gram)]
+}
+
+/*
+	Score returns the average log probability of each run of N letters in
+	the text, which must only contain the letters A-Z. Higher is more like
+	the language.
+*/
+func (m *Model) Score(text string) float64 {
+	if len(text) < m.N {
+		return 0
+	}
+	// Roll the index along the text rather than working it out each time.
+	size := len(m.logProbabilities) / 26
+	i := index(text[:m.N-1])
+	score := 0.0
+	for j := m.N - 1; j < len(text); j++ {
+		i = (i%size)*26 + int(text[j]-'A')
+		score += m.logProbabilities[i]
+	}
+	return score / float64(len(text)-m.N+1)
+}
+
+/*
+	Fingerprint identifies the model by a hash of its table, so that a
+	search can tell whether it is resumed with the same model.
+*/
+func (m *Model) Fingerprint() string {
+	h := fnv.New64a()
+	var b [8]byte
+	for _, p := range m.logProbabilities {
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(p))
+		h.Write(b[:])
+	}
+	return fmt.Sprintf("%d-gram %016x", m.N, h.Sum64())
+}
+
+var (
+	//go:embed data/english-bigrams.txt
+	englishBigrams string
+	//go:embed data/english-trigrams.txt
+	englishTrigrams string
+	//go:embed data/english-quadgrams.txt
+	englishQuadgrams string
+
+	englishOnce   sync.Once
+	englishModels map[int]*Model
+)
+
+/*
+	English returns a model of English for runs of 2, 3 or 4 letters,
+	counted from Newton's Opticks.
+*/
+func English(n int) (*Model, error) {
+	englishOnce.Do(func() {
+		englishModels = make(map[int]*Model)
+		for n, text := range map[int]string{2: englishBigrams, 3: englishTrigrams, 4: englishQuadgrams} {
+			m, err := ReadText(strings.NewReader(text))
+			if err != nil {
+				panic("ngram: reading the English tables: " + err.Error())
+			}
+			englishModels[n] = m
+		}
+	})
+	if m, ok := englishModels[n]; ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("there is no English model for runs of %d letters", n)
+}
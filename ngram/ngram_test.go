@@ -0,0 +1,78 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package ngram
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestReadText(t *testing.T) {
+	m, err := ReadText(strings.NewReader("AB 3\nba 1\n\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if m.N != 2 {
+		t.Errorf("Expected 2, got %d", m.N)
+	}
+	for _, test := range []struct {
+		gram     string
+		expected float64
+	}{
+		{"AB", math.Log10(0.75)},
+		{"BA", math.Log10(0.25)},
+		{"ZZ", math.Log10(0.025)},
+	} {
+		if p := m.LogProbability(test.gram); math.Abs(p-test.expected) > 1e-9 {
+			t.Errorf("Expected %f for %s, got %f", test.expected, test.gram, p)
+		}
+	}
+
+	for _, invalid := range []string{"", "AB", "AB 1\nABC 1", "A1 2", "AB x", "AB -1"} {
+		if _, err := ReadText(strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected an error reading %q", invalid)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	m, _ := ReadText(strings.NewReader("AB 3\nBA 1\n"))
+	expected := (2*math.Log10(0.75) + math.Log10(0.25)) / 3
+	if s := m.Score("ABAB"); math.Abs(s-expected) > 1e-9 {
+		t.Errorf("Expected %f, got %f", expected, s)
+	}
+	if s := m.Score("A"); s != 0 {
+		t.Errorf("Expected 0 for a text shorter than a run, got %f", s)
+	}
+}
+
+func TestEnglish(t *testing.T) {
+	for n := 2; n <= 4; n++ {
+		m, err := English(n)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		english := m.Score("THEQUICKBROWNFOXJUMPSOVERTHELAZYDOG")
+		shuffled := m.Score("QTHEUICBKRWONOFXJMUPSVOETRHLEAZYODG")
+		if english <= shuffled {
+			t.Errorf("Expected English to score higher with %d letters, got %f and %f", n, english, shuffled)
+		}
+	}
+	if _, err := English(5); err == nil {
+		t.Errorf("Expected an error for runs of 5 letters")
+	}
+}
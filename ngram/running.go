@@ -0,0 +1,57 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package ngram
+
+/*
+	A Running score is worked out a letter at a time, so a candidate can be
+	given up on part way through once it can't catch up with the best.
+*/
+type Running struct {
+	m              *Model
+	index, letters int
+	sum            float64
+}
+
+// Running returns an empty running score.
+func (m *Model) Running() *Running {
+	return &Running{m: m}
+}
+
+// Add adds the next letter, which must be from A-Z.
+func (r *Running) Add(letter byte) {
+	r.index = (r.index*26 + int(letter-'A')) % len(r.m.logProbabilities)
+	if r.letters++; r.letters >= r.m.N {
+		r.sum += r.m.logProbabilities[r.index]
+	}
+}
+
+// Sum returns the total log probability of the runs so far.
+func (r *Running) Sum() float64 {
+	return r.sum
+}
+
+// Score returns the same as Model.Score for the letters added so far.
+func (r *Running) Score() float64 {
+	if r.letters < r.m.N {
+		return 0
+	}
+	return r.sum / float64(r.letters-r.m.N+1)
+}
+
+// Reset empties the score to start again.
+func (r *Running) Reset() {
+	r.index, r.letters, r.sum = 0, 0, 0
+}
@@ -0,0 +1,95 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package ngram
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+/*
+	A Trainer counts the runs of letters in a corpus. Write text to it, in
+	any case and with any punctuation, which is dropped. Runs carry on
+	across spaces and punctuation, like messages written without them.
+*/
+type Trainer struct {
+	n      int
+	counts []uint64
+	total  uint64
+
+	// The index of the last n letters, and how many letters there have been.
+	index, letters int
+}
+
+func NewTrainer(n int) (*Trainer, error) {
+	if n < 1 || n > MaxN {
+		return nil, fmt.Errorf("runs must be 1 to %d letters, not %d", MaxN, n)
+	}
+	return &Trainer{n: n, counts: make([]uint64, size(n))}, nil
+}
+
+func (t *Trainer) Write(p []byte) (int, error) {
+	runs := len(t.counts)
+	for _, c := range p {
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		if c < 'A' || c > 'Z' {
+			continue
+		}
+		t.index = (t.index*26 + int(c-'A')) % runs
+		if t.letters++; t.letters >= t.n {
+			t.counts[t.index]++
+			t.total++
+		}
+	}
+	return len(p), nil
+}
+
+// Model returns a model of the text written so far.
+func (t *Trainer) Model() (*Model, error) {
+	if t.total == 0 {
+		return nil, fmt.Errorf("no runs of %d letters to train on", t.n)
+	}
+	counts := make([]float64, len(t.counts))
+	for i, c := range t.counts {
+		counts[i] = float64(c)
+	}
+	return newModel(t.n, counts, float64(t.total)), nil
+}
+
+// WriteText writes the counts in the form ReadText reads, most common first.
+func (t *Trainer) WriteText(w io.Writer) error {
+	var seen []int
+	for i, c := range t.counts {
+		if c > 0 {
+			seen = append(seen, i)
+		}
+	}
+	sort.SliceStable(seen, func(a, b int) bool { return t.counts[seen[a]] > t.counts[seen[b]] })
+
+	buf := bufio.NewWriter(w)
+	gram := make([]byte, t.n)
+	for _, i := range seen {
+		for j, k := t.n-1, i; j >= 0; j, k = j-1, k/26 {
+			gram[j] = byte('A' + k%26)
+		}
+		fmt.Fprintf(buf, "%s %d\n", gram, t.counts[i])
+	}
+	return buf.Flush()
+}
@@ -0,0 +1,149 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package ngram
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrainer(t *testing.T) {
+	tr, err := NewTrainer(2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	tr.Write([]byte("Ab, "))
+	tr.Write([]byte("ab!"))
+
+	var buf bytes.Buffer
+	if err := tr.WriteText(&buf); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if expected := "AB 2\nBA 1\n"; buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+
+	m, err := tr.Model()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if p := m.LogProbability("AB"); math.Abs(p-math.Log10(2.0/3)) > 1e-9 {
+		t.Errorf("Expected %f, got %f", math.Log10(2.0/3), p)
+	}
+
+	if _, err := NewTrainer(MaxN + 1); err == nil {
+		t.Errorf("Expected an error for runs that are too long")
+	}
+	empty, _ := NewTrainer(3)
+	empty.Write([]byte("AB"))
+	if _, err := empty.Model(); err == nil {
+		t.Errorf("Expected an error for too little text")
+	}
+}
+
+func TestBinary(t *testing.T) {
+	m, _ := English(3)
+	var buf bytes.Buffer
+	if err := m.WriteBinary(&buf); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if expected := len(magic) + 1 + 2*26*26*26; buf.Len() != expected {
+		t.Errorf("Expected %d bytes, got %d", expected, buf.Len())
+	}
+
+	read, err := ReadBinary(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, gram := range []string{"THE", "ING", "QQQ"} {
+		if d := math.Abs(read.LogProbability(gram) - m.LogProbability(gram)); d > 0.001 {
+			t.Errorf("Expected %f for %s, got %f", m.LogProbability(gram), gram, read.LogProbability(gram))
+		}
+	}
+
+	if _, err := ReadBinary(strings.NewReader("THE 1\n")); err == nil {
+		t.Errorf("Expected an error reading text as binary")
+	}
+	if _, err := ReadBinary(bytes.NewReader(buf.Bytes()[:100])); err == nil {
+		t.Errorf("Expected an error reading a short file")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ngram")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m, _ := English(2)
+	var buf bytes.Buffer
+	m.WriteBinary(&buf)
+	binaryFile := filepath.Join(dir, "model.bin")
+	textFile := filepath.Join(dir, "model.txt")
+	ioutil.WriteFile(binaryFile, buf.Bytes(), 0644)
+	ioutil.WriteFile(textFile, []byte("TH 3\nHE 2\n"), 0644)
+
+	for _, path := range []string{binaryFile, textFile} {
+		loaded, err := Load(path)
+		if err != nil {
+			t.Fatalf("Unexpected error loading %s: %s", path, err)
+		}
+		if loaded.N != 2 {
+			t.Errorf("Expected 2 from %s, got %d", path, loaded.N)
+		}
+	}
+	if _, err := Load(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
+
+func TestRunning(t *testing.T) {
+	m, _ := English(4)
+	text := "THEQUICKBROWNFOXJUMPSOVERTHELAZYDOG"
+	r := m.Running()
+	for i := 0; i < len(text); i++ {
+		r.Add(text[i])
+		if math.Abs(r.Score()-m.Score(text[:i+1])) > 1e-9 {
+			t.Errorf("Expected %f after %d letters, got %f", m.Score(text[:i+1]), i+1, r.Score())
+		}
+	}
+	r.Reset()
+	if r.Score() != 0 || r.Sum() != 0 {
+		t.Errorf("Expected an empty score after Reset")
+	}
+}
+
+func BenchmarkLoadText(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ReadText(strings.NewReader(englishQuadgrams))
+	}
+}
+
+func BenchmarkLoadBinary(b *testing.B) {
+	m, _ := English(4)
+	var buf bytes.Buffer
+	m.WriteBinary(&buf)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ReadBinary(bytes.NewReader(buf.Bytes()))
+	}
+}
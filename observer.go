@@ -0,0 +1,109 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package enigma
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// The parts of the machine a signal passes through.
+type Component int
+
+const (
+	PlugboardComponent Component = iota
+	RightRotorComponent
+	MiddleRotorComponent
+	LeftRotorComponent
+	ReflectorComponent
+)
+
+var componentNames = []string{"plugboard", "right rotor", "middle rotor",
+	"left rotor", "reflector"}
+
+func (c Component) String() string {
+	return componentNames[c]
+}
+
+/*
+	An Observer is told what happens inside the machine during each Step. The
+	machine only does this extra work when an observer has been set with
+	SetObserver.
+*/
+type Observer interface {
+	// Called once the rotors have moved, with the window letters before and
+	// after the move.
+	Stepped(before, after string)
+
+	// Called in order for every component the signal passes through, on the
+	// way in and again on the way back, with the letter entering and leaving.
+	// The plugboard is skipped when the machine has no plugs.
+	Passed(c Component, in, out rune)
+}
+
+type Hop struct {
+	Component Component
+	In, Out   rune
+}
+
+/*
+	A Trace is an Observer that remembers the path taken by the most recent
+	key press so it can be printed.
+*/
+type Trace struct {
+	Before, After string
+	Hops          []Hop
+}
+
+func (t *Trace) Stepped(before, after string) {
+	t.Before, t.After = before, after
+	t.Hops = t.Hops[:0]
+}
+
+func (t *Trace) Passed(c Component, in, out rune) {
+	t.Hops = append(t.Hops, Hop{c, in, out})
+}
+
+// Key returns the key that was pressed, or 0 if nothing has been traced.
+func (t *Trace) Key() rune {
+	if len(t.Hops) == 0 {
+		return 0
+	}
+	return t.Hops[0].In
+}
+
+// Lamp returns the lamp that lit, or 0 if nothing has been traced.
+func (t *Trace) Lamp() rune {
+	if len(t.Hops) == 0 {
+		return 0
+	}
+	return t.Hops[len(t.Hops)-1].Out
+}
+
+// String renders the lamp path with one component per line.
+func (t *Trace) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%-14s%s -> %s\n", "rotors", t.Before, t.After)
+	if len(t.Hops) == 0 {
+		return buf.String()
+	}
+	fmt.Fprintf(&buf, "%-14s%c\n", "key", t.Key())
+	for _, h := range t.Hops {
+		fmt.Fprintf(&buf, "%-14s%c -> %c\n", h.Component, h.In, h.Out)
+	}
+	fmt.Fprintf(&buf, "%-14s%c\n", "lamp", t.Lamp())
+	return buf.String()
+}
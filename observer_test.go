@@ -0,0 +1,80 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package enigma
+
+import "testing"
+
+func TestTrace(t *testing.T) {
+	m := NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'A', 'A')
+	trace := new(Trace)
+	m.SetObserver(trace)
+	assertEqualsRune(t, 'B', m.Step('A'))
+
+	if trace.Before != "AAA" || trace.After != "AAB" {
+		t.Errorf("Expected AAA -> AAB, got %s -> %s", trace.Before, trace.After)
+	}
+	expected := []Hop{
+		{RightRotorComponent, 'A', 'C'},
+		{MiddleRotorComponent, 'C', 'D'},
+		{LeftRotorComponent, 'D', 'F'},
+		{ReflectorComponent, 'F', 'S'},
+		{LeftRotorComponent, 'S', 'S'},
+		{MiddleRotorComponent, 'S', 'E'},
+		{RightRotorComponent, 'E', 'B'},
+	}
+	if len(trace.Hops) != len(expected) {
+		t.Fatalf("Expected %d hops, got %d", len(expected), len(trace.Hops))
+	}
+	for i, h := range expected {
+		if trace.Hops[i] != h {
+			t.Errorf("Expected %v, got %v", h, trace.Hops[i])
+		}
+	}
+	assertEqualsRune(t, 'A', trace.Key())
+	assertEqualsRune(t, 'B', trace.Lamp())
+
+	expectedString := "rotors        AAA -> AAB\n" +
+		"key           A\n" +
+		"right rotor   A -> C\n" +
+		"middle rotor  C -> D\n" +
+		"left rotor    D -> F\n" +
+		"reflector     F -> S\n" +
+		"left rotor    S -> S\n" +
+		"middle rotor  S -> E\n" +
+		"right rotor   E -> B\n" +
+		"lamp          B\n"
+	if trace.String() != expectedString {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expectedString, trace.String())
+	}
+}
+
+func TestTraceWithPlugboard(t *testing.T) {
+	m := NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'A', 'A')
+	p, _ := NewPlugboard("AQ BZ")
+	m.SetPlugboard(p)
+	trace := new(Trace)
+	m.SetObserver(trace)
+
+	unobserved := NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'A', 'A')
+	unobserved.SetPlugboard(p)
+	for _, c := range "HELLOWORLD" {
+		assertEqualsRune(t, unobserved.Step(c), m.Step(c))
+		assertEqualsInt(t, 9, len(trace.Hops))
+	}
+	if trace.Hops[0].Component != PlugboardComponent {
+		t.Errorf("Expected the plugboard first, got %s", trace.Hops[0].Component)
+	}
+}
@@ -0,0 +1,368 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package plaintext
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+)
+
+/*
+	A Convention describes how operators wrote ordinary text using only the
+	letters A-Z. A Convention implements enigma.Codec so it can be passed
+	straight to Machine.EncryptText and Machine.DecryptText.
+*/
+type Convention struct {
+	// Written between words, "X" for most German traffic.
+	Separator string
+
+	// Punctuation marks and the letters written in their place. A mark takes
+	// the place of the separator that would otherwise follow the word.
+	Punctuation map[rune]string
+
+	// The spelled out form of the digits 0-9. Numbers are spelled one digit
+	// at a time.
+	Digits [10]string
+
+	// Full words and the abbreviation the operators used for them.
+	Abbreviations map[string]string
+
+	// Letter groups replaced everywhere, applied in order, e.g. CH by Q.
+	Substitutions []Substitution
+
+	/*
+		Written before a letter of a word that would otherwise be read as
+		the start of the separator, a punctuation mark or a substitution,
+		and before a letter that is the escape itself. It is also written
+		before the first plain letter of a word that would be read as a
+		number or an abbreviation. With it Decode gets every word back as it
+		was. Operators had no such thing, so the conventions here leave it
+		empty, but e.g. "J", which is rare in German, can be set to opt in.
+	*/
+	Escape string
+}
+
+type Substitution struct {
+	From, To string
+}
+
+var germanDigits = [10]string{"NULL", "EINS", "ZWO", "DREI", "VIER", "FUENF",
+	"SECHS", "SIEBEN", "ACHT", "NEUN"}
+
+var englishDigits = [10]string{"ZERO", "ONE", "TWO", "THREE", "FOUR", "FIVE",
+	"SIX", "SEVEN", "EIGHT", "NINE"}
+
+// The maps are made afresh for each convention so changing one leaves the others alone.
+func germanPunctuation() map[rune]string {
+	return map[rune]string{
+		'.': "X",
+		',': "Y",
+		'?': "UD",
+		':': "XX",
+		'-': "YY",
+		'(': "KK",
+		')': "KK",
+	}
+}
+
+func germanAbbreviations() map[string]string {
+	return map[string]string{
+		"ABTEILUNG":         "ABT",
+		"ARMEEOBERKOMMANDO": "AOK",
+		"BATAILLON":         "BTL",
+		"DIVISION":          "DIV",
+		"GENERALKOMMANDO":   "GENKDO",
+		"INFANTERIE":        "INF",
+		"KOMMANDEUR":        "KDR",
+		"KOMMANDO":          "KDO",
+		"KOMPANIE":          "KP",
+		"OBERKOMMANDO":      "OBKDO",
+		"PANZER":            "PZ",
+		"REGIMENT":          "RGT",
+	}
+}
+
+// German returns the conventions of German Army and Air Force traffic.
+func German() *Convention {
+	return &Convention{
+		Separator:     "X",
+		Punctuation:   germanPunctuation(),
+		Digits:        germanDigits,
+		Abbreviations: germanAbbreviations(),
+	}
+}
+
+/*
+	Naval returns the conventions of German Navy traffic, which are the same as
+	German() except that CH is always written as Q.
+*/
+func Naval() *Convention {
+	c := German()
+	c.Substitutions = []Substitution{{"CH", "Q"}}
+	return c
+}
+
+// English applies the German conventions to English text.
+func English() *Convention {
+	return &Convention{
+		Separator:   "X",
+		Punctuation: germanPunctuation(),
+		Digits:      englishDigits,
+	}
+}
+
+/*
+	Encode converts text into plaintext that can be typed into the machine.
+	Umlauts and accents are written out, numbers are spelled, words are
+	abbreviated and anything without a convention is dropped. If the
+	convention has an Escape, letters of a word that Decode would misread
+	are escaped.
+*/
+func (c *Convention) Encode(text string) string {
+	var out bytes.Buffer
+	var word []rune
+	isNumber, needSeparator := false, false
+
+	endWord := func() {
+		if len(word) == 0 {
+			return
+		}
+		if needSeparator {
+			out.WriteString(c.Separator)
+		}
+		if isNumber {
+			for _, d := range word {
+				out.WriteString(c.encodeWord(c.Digits[d-'0'], false))
+			}
+		} else if short, ok := c.Abbreviations[string(word)]; ok {
+			out.WriteString(c.encodeWord(short, false))
+		} else {
+			out.WriteString(c.encodeWord(string(word), c.misread(string(word))))
+		}
+		word = word[:0]
+		needSeparator = true
+	}
+
+	for _, r := range transliterate(text) {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			digit := r <= '9'
+			if len(word) > 0 && digit != isNumber {
+				endWord()
+			}
+			isNumber = digit
+			word = append(word, r)
+		case unicode.IsSpace(r):
+			endWord()
+		default:
+			if code, ok := c.Punctuation[r]; ok {
+				endWord()
+				out.WriteString(code)
+				needSeparator = false
+			}
+		}
+	}
+	endWord()
+	return out.String()
+}
+
+/*
+	Escapes the letters of a word that Decode would misread, and the first
+	plain letter too if the word is literal, then makes the substitutions.
+*/
+func (c *Convention) encodeWord(word string, literal bool) string {
+	if c.Escape != "" {
+		var buf bytes.Buffer
+		for _, r := range word {
+			if c.special(r) || literal {
+				buf.WriteString(c.Escape)
+				literal = literal && c.special(r)
+			}
+			buf.WriteRune(r)
+		}
+		word = buf.String()
+	}
+	for _, sub := range c.Substitutions {
+		word = strings.Replace(word, sub.From, sub.To, -1)
+	}
+	return word
+}
+
+// Whether a letter has to be escaped when it is part of a word.
+func (c *Convention) special(r rune) bool {
+	if strings.ContainsRune(c.Separator, r) || strings.ContainsRune(c.Escape, r) {
+		return true
+	}
+	for _, sub := range c.Substitutions {
+		if strings.ContainsRune(sub.To, r) {
+			return true
+		}
+	}
+	for _, code := range c.Punctuation {
+		if strings.IndexRune(code, r) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether Decode would read a word of the text as a number or an abbreviation.
+func (c *Convention) misread(word string) bool {
+	if _, ok := c.number(word); ok {
+		return true
+	}
+	for _, short := range c.Abbreviations {
+		if short == word {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	Decode renders decrypted plaintext as readable words: separators become
+	spaces, substitutions are undone, spelled numbers become digits and
+	abbreviations are expanded. Punctuation is not restored.
+
+	Without an Escape this is lossy, as the conventions were. Every X splits
+	a word, every letter group a substitution writes is undone, letters
+	written for punctuation are read as part of the words around them and
+	any word spelled from digits, like ACHT, becomes digits. With an Escape
+	the words come back as they were, except that punctuation marks only
+	split them.
+*/
+func (c *Convention) Decode(plaintext string) string {
+	expansions := make(map[string]string, len(c.Abbreviations))
+	for full, short := range c.Abbreviations {
+		expansions[short] = full
+	}
+
+	var result []string
+	var word bytes.Buffer
+	start := 0 // Where the word starts in the plaintext.
+	endWord := func(end int) {
+		if word.Len() == 0 {
+			return
+		}
+		w := word.String()
+		word.Reset()
+		// A literal word was written with an extra escape.
+		if c.Escape == "" || plaintext[start:end] == c.encodeWord(w, false) {
+			if n, ok := c.number(w); ok {
+				w = n
+			} else if full, ok := expansions[w]; ok {
+				w = full
+			}
+		}
+		result = append(result, w)
+	}
+
+	for i := 0; i < len(plaintext); {
+		s := plaintext[i:]
+		if word.Len() == 0 {
+			start = i
+		}
+		if c.Escape != "" && len(s) > len(c.Escape) && strings.HasPrefix(s, c.Escape) {
+			word.WriteByte(s[len(c.Escape)])
+			i += len(c.Escape) + 1
+		} else if n := c.boundary(s); n > 0 {
+			endWord(i)
+			i += n
+		} else if from, to, ok := c.substitution(s); ok {
+			word.WriteString(from)
+			i += len(to)
+		} else {
+			word.WriteByte(s[0])
+			i++
+		}
+	}
+	endWord(len(plaintext))
+	return strings.Join(result, " ")
+}
+
+/*
+	Returns the length of the separator, or with an Escape the longest
+	punctuation mark, at the start of s, or 0 if there is neither.
+*/
+func (c *Convention) boundary(s string) int {
+	n := 0
+	if c.Separator != "" && strings.HasPrefix(s, c.Separator) {
+		n = len(c.Separator)
+	}
+	if c.Escape != "" {
+		for _, code := range c.Punctuation {
+			if len(code) > n && strings.HasPrefix(s, code) {
+				n = len(code)
+			}
+		}
+	}
+	return n
+}
+
+// Finds the substitution made at the start of s, trying the last one first.
+func (c *Convention) substitution(s string) (from, to string, ok bool) {
+	for i := len(c.Substitutions) - 1; i >= 0; i-- {
+		sub := c.Substitutions[i]
+		if sub.To != "" && strings.HasPrefix(s, sub.To) {
+			return sub.From, sub.To, true
+		}
+	}
+	return "", "", false
+}
+
+// Returns the digits if the word is made up entirely of spelled digits.
+func (c *Convention) number(word string) (string, bool) {
+	var buf bytes.Buffer
+	for len(word) > 0 {
+		found := false
+		for d, spelled := range c.Digits {
+			if spelled != "" && strings.HasPrefix(word, spelled) {
+				buf.WriteByte(byte('0' + d))
+				word = word[len(spelled):]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", false
+		}
+	}
+	return buf.String(), buf.Len() > 0
+}
+
+var replacements = map[rune]string{
+	'Ä': "AE", 'Ö': "OE", 'Ü': "UE", 'ß': "SS",
+	'À': "A", 'Á': "A", 'Â': "A", 'Ç': "C", 'È': "E", 'É': "E", 'Ê': "E",
+	'Ì': "I", 'Í': "I", 'Î': "I", 'Ñ': "N", 'Ò': "O", 'Ó': "O", 'Ô': "O",
+	'Ù': "U", 'Ú': "U", 'Û': "U",
+}
+
+// Converts text to upper case and writes out umlauts and accented letters.
+func transliterate(text string) string {
+	var buf bytes.Buffer
+	for _, r := range text {
+		if r != 'ß' {
+			r = unicode.ToUpper(r)
+		}
+		if s, ok := replacements[r]; ok {
+			buf.WriteString(s)
+		} else {
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,125 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package plaintext
+
+import (
+	"testing"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+func assertEqualsString(t *testing.T, expected, actual string) {
+	if expected != actual {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+}
+
+func TestEncodeGerman(t *testing.T) {
+	c := German()
+	assertEqualsString(t, "WETTERXFUERXHEUTE", c.Encode("Wetter für heute"))
+	assertEqualsString(t, "ANXOBKDOXDERXMARINEX", c.Encode("An Oberkommando der Marine."))
+	assertEqualsString(t, "UMXZWONULLDREINULLXUHRXREGENYKALT",
+		c.Encode("Um 2030 Uhr Regen, kalt"))
+	assertEqualsString(t, "UXEINSVIERSIEBEN", c.Encode("U147"))
+	assertEqualsString(t, "STRASSE", c.Encode("Straße"))
+}
+
+func TestEncodeNaval(t *testing.T) {
+	c := Naval()
+	assertEqualsString(t, "NAQTXSEQSXAQT", c.Encode("Nacht 6 8"))
+}
+
+func TestEncodeEnglish(t *testing.T) {
+	c := English()
+	assertEqualsString(t, "ATTACKXATXFIVEZEROX", c.Encode("Attack at 50."))
+}
+
+func TestDecode(t *testing.T) {
+	assertEqualsString(t, "AN OBERKOMMANDO DER MARINE",
+		German().Decode("ANXOBKDOXDERXMARINEX"))
+	assertEqualsString(t, "UM 2030 UHR", German().Decode("UMXZWONULLDREINULLXUHR"))
+	assertEqualsString(t, "NACHT 6 8", Naval().Decode("NAQTXSEQSXAQT"))
+	assertEqualsString(t, "ACHTUNG", German().Decode("ACHTUNG"))
+}
+
+// The conventions with the escape J.
+func escaped(c *Convention) *Convention {
+	c.Escape = "J"
+	return c
+}
+
+func TestEncodeEscapes(t *testing.T) {
+	// The operators wrote J as it was.
+	assertEqualsString(t, "JAYDERXJAEGERXHATXACHTXXYLOPHONE",
+		German().Encode("Ja, der Jäger hat acht Xylophone"))
+	assertEqualsString(t, "JJAXEJXTRA", escaped(German()).Encode("Ja extra"))
+	assertEqualsString(t, "JQJUELLEXNAQT", escaped(Naval()).Encode("Quelle Nacht"))
+	// A word that would be read as a number or abbreviation is marked.
+	assertEqualsString(t, "JACHTXACHTXJABTXABT", escaped(German()).Encode("acht 8 ABT Abteilung"))
+}
+
+func TestDecodeLossy(t *testing.T) {
+	// The comma's Y joins two words, ACHT becomes 8 and the X of XYLOPHONE splits it.
+	assertEqualsString(t, "JAYDER JAEGER HAT 8 YLOPHONE",
+		German().Decode(German().Encode("Ja, der Jäger hat acht Xylophone")))
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		c        *Convention
+		text     string
+		expected string
+	}{
+		{German(), "Ja, der Jäger hat acht Xylophone", "JA DER JAEGER HAT ACHT XYLOPHONE"},
+		{German(), "Extra Taxi für Jäger", "EXTRA TAXI FUER JAEGER"},
+		{German(), "Quelle am Quai in 6 Jahren.", "QUELLE AM QUAI IN 6 JAHREN"},
+		{German(), "Kurz (yes) und gut? 2 Kriege: ABT, Abteilung - Uhu", "KURZ YES UND GUT 2 KRIEGE ABT ABTEILUNG UHU"},
+		{Naval(), "Nachschub für Quax und Jochen", "NACHSCHUB FUER QUAX UND JOCHEN"},
+		{Naval(), "Jeder Quirl mixt acht Sachen, nicht 8", "JEDER QUIRL MIXT ACHT SACHEN NICHT 8"},
+		{English(), "Jack quickly fixed the jukebox, one of 1", "JACK QUICKLY FIXED THE JUKEBOX ONE OF 1"},
+	}
+	for _, test := range tests {
+		c := escaped(test.c)
+		assertEqualsString(t, test.expected, c.Decode(c.Encode(test.text)))
+	}
+}
+
+func TestConventionsDontShare(t *testing.T) {
+	c := German()
+	c.Punctuation['!'] = "XX"
+	c.Abbreviations["FLOTTE"] = "FL"
+	if _, ok := Naval().Punctuation['!']; ok {
+		t.Errorf("Expected a change to German() to leave Naval() alone")
+	}
+	if _, ok := German().Abbreviations["FLOTTE"]; ok {
+		t.Errorf("Expected a change to German() to leave the next German() alone")
+	}
+	if _, ok := English().Punctuation['!']; ok {
+		t.Errorf("Expected a change to German() to leave English() alone")
+	}
+}
+
+func TestMachineRoundTrip(t *testing.T) {
+	text := "Kommandeur an 7. Division: Angriff um 0600 Uhr"
+	m := enigma.NewMachine(enigma.Rotor2(), enigma.Rotor1(), enigma.Rotor3(),
+		enigma.ReflectorB(), 'W', 'X', 'C')
+	encrypted := m.EncryptText(text, German())
+
+	m = enigma.NewMachine(enigma.Rotor2(), enigma.Rotor1(), enigma.Rotor3(),
+		enigma.ReflectorB(), 'W', 'X', 'C')
+	assertEqualsString(t, "KOMMANDEUR AN 7 DIVISION ANGRIFF UM 0600 UHR",
+		m.DecryptText(encrypted, German()))
+}
@@ -0,0 +1,77 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package enigma
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+/*
+	A Plugboard swaps pairs of letters as the signal enters and leaves the
+	rotors. Letters without a plug are left alone.
+*/
+type Plugboard struct {
+	mapping [26]int32
+}
+
+/*
+	NewPlugboard creates a plugboard from space separated pairs of letters,
+	e.g. "AB CD EF".
+*/
+func NewPlugboard(pairs string) (*Plugboard, error) {
+	p := Plugboard{}
+	for i := range p.mapping {
+		p.mapping[i] = int32(i)
+	}
+
+	for _, pair := range strings.Fields(strings.ToUpper(pairs)) {
+		if len(pair) != 2 || !isLetter(rune(pair[0])) || !isLetter(rune(pair[1])) {
+			return nil, fmt.Errorf("invalid plugboard pair %q", pair)
+		}
+		a, b := int32(pair[0]-'A'), int32(pair[1]-'A')
+		if a == b || p.mapping[a] != a || p.mapping[b] != b {
+			return nil, fmt.Errorf("letter used more than once in plugboard pair %q", pair)
+		}
+		p.mapping[a], p.mapping[b] = b, a
+	}
+	return &p, nil
+}
+
+// Get returns the letter the given letter is plugged to.
+func (p *Plugboard) Get(letter rune) rune {
+	return LETTERS[p.mapping[letter-'A']]
+}
+
+// String returns the pairs in the same form NewPlugboard accepts.
+func (p *Plugboard) String() string {
+	var buf bytes.Buffer
+	for i, j := range p.mapping {
+		if int32(i) < j {
+			if buf.Len() > 0 {
+				buf.WriteRune(' ')
+			}
+			buf.WriteRune(LETTERS[i])
+			buf.WriteRune(LETTERS[j])
+		}
+	}
+	return buf.String()
+}
+
+func isLetter(r rune) bool {
+	return r >= 'A' && r <= 'Z'
+}
@@ -0,0 +1,48 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package enigma
+
+import "testing"
+
+func TestNewPlugboard(t *testing.T) {
+	p, err := NewPlugboard("ab QZ")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assertEqualsRune(t, 'B', p.Get('A'))
+	assertEqualsRune(t, 'A', p.Get('B'))
+	assertEqualsRune(t, 'Z', p.Get('Q'))
+	assertEqualsRune(t, 'C', p.Get('C'))
+	if p.String() != "AB QZ" {
+		t.Errorf("Expected AB QZ, got %s", p.String())
+	}
+
+	for _, invalid := range []string{"AB AC", "AA", "ABC", "A1"} {
+		if _, err := NewPlugboard(invalid); err == nil {
+			t.Errorf("Expected an error for %s", invalid)
+		}
+	}
+}
+
+func TestStepWithPlugboard(t *testing.T) {
+	// Instruction manual example: rotors I, II, III, rings AAA, position AAA
+	// and plugs AB: A is sent through the rotors as B.
+	m := NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'A', 'A')
+	p, _ := NewPlugboard("AB")
+	m.SetPlugboard(p)
+	plain := NewMachine(Rotor1(), Rotor2(), Rotor3(), ReflectorB(), 'A', 'A', 'A')
+	assertEqualsRune(t, plain.Permutation()[1], p.Get(m.Step('A')))
+}
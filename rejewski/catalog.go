@@ -0,0 +1,207 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package rejewski
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+// The ground settings of each wheel order and reflector.
+const positions = 26 * 26 * 26
+
+type Options struct {
+	// The rotors to choose wheel orders from, by name. Defaults to I, II
+	// and III, all there were until 1938.
+	Rotors []string
+
+	// The reflectors to include, by name. Defaults to B.
+	Reflectors []string
+}
+
+func (o *Options) setDefaults() {
+	if len(o.Rotors) == 0 {
+		o.Rotors = []string{"I", "II", "III"}
+	}
+	if len(o.Reflectors) == 0 {
+		o.Reflectors = []string{"B"}
+	}
+}
+
+/*
+	A Catalog holds the characteristic of every wheel order, reflector and
+	ground setting, with the rings at AAA. The rings only shift the ground
+	setting, apart from when the middle rotor turns over within the 6
+	letters of an indicator.
+*/
+type Catalog struct {
+	Rotors     []string `json:"rotors"`
+	Reflectors []string `json:"reflectors"`
+
+	// The settings of each characteristic, numbered by setting.
+	Entries map[string][]int32 `json:"entries"`
+
+	orders [][3]int
+}
+
+// Build works out the characteristic of every setting, which takes a while.
+func Build(opts Options) (*Catalog, error) {
+	opts.setDefaults()
+	c := &Catalog{Rotors: opts.Rotors, Reflectors: opts.Reflectors, Entries: make(map[string][]int32)}
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+
+	for i, o := range c.orders {
+		for j, name := range c.Reflectors {
+			r1, _ := enigma.RotorByName(c.Rotors[o[0]])
+			r2, _ := enigma.RotorByName(c.Rotors[o[1]])
+			r3, _ := enigma.RotorByName(c.Rotors[o[2]])
+			reflector, _ := enigma.ReflectorByName(name)
+			m := enigma.NewMachine(r1, r2, r3, reflector, 'A', 'A', 'A')
+			unit := int32((i*len(c.Reflectors) + j) * positions)
+			for p := int32(0); p < positions; p++ {
+				m.SetPositions('A'+p/676, 'A'+p/26%26, 'A'+p%26)
+				key := characteristicAt(m).String()
+				c.Entries[key] = append(c.Entries[key], unit+p)
+			}
+			enigma.FreeMachine(m)
+		}
+	}
+	return c, nil
+}
+
+// The characteristic of the indicators enciphered from the machine's position.
+func characteristicAt(m *enigma.Machine) Characteristic {
+	var presses [6]enigma.Permutation
+	for i := range presses {
+		presses[i] = m.Permutation()
+		m.Step('A')
+	}
+	// Each permutation is its own inverse, so AD takes the first letter of
+	// an indicator back to the message key and on to the fourth.
+	var ps [3]enigma.Permutation
+	for i := range ps {
+		for x := range ps[i] {
+			ps[i][x] = presses[i+3][presses[i][x]-'A']
+		}
+	}
+	return characteristicOf(ps)
+}
+
+// Checks the rotors and reflectors and lists the wheel orders.
+func (c *Catalog) init() error {
+	for _, name := range c.Rotors {
+		if _, err := enigma.RotorByName(name); err != nil {
+			return err
+		}
+	}
+	for _, name := range c.Reflectors {
+		if _, err := enigma.ReflectorByName(name); err != nil {
+			return err
+		}
+	}
+	c.orders = nil
+	for a := range c.Rotors {
+		for b := range c.Rotors {
+			for d := range c.Rotors {
+				if a != b && a != d && b != d {
+					c.orders = append(c.orders, [3]int{a, b, d})
+				}
+			}
+		}
+	}
+	if len(c.orders) == 0 {
+		return fmt.Errorf("need at least 3 rotors, got %d", len(c.Rotors))
+	}
+	return nil
+}
+
+// Lookup returns the keys, without rings or plugs, with the characteristic.
+func (c *Catalog) Lookup(ch Characteristic) []enigma.Key {
+	var keys []enigma.Key
+	for _, s := range c.Entries[ch.String()] {
+		unit, p := int(s)/positions, s%positions
+		o := c.orders[unit/len(c.Reflectors)]
+		keys = append(keys, enigma.Key{
+			Reflector: c.Reflectors[unit%len(c.Reflectors)],
+			Rotors:    []string{c.Rotors[o[0]], c.Rotors[o[1]], c.Rotors[o[2]]},
+			Positions: string([]rune{'A' + p/676, 'A' + p/26%26, 'A' + p%26}),
+		})
+	}
+	return keys
+}
+
+// Save writes the catalog as JSON, through a temporary file.
+func (c *Catalog) Save(path string) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load reads a catalog written by Save.
+func Load(path string) (*Catalog, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("reading %s: %s", path, err)
+	}
+	if err := c.init(); err != nil {
+		return nil, fmt.Errorf("reading %s: %s", path, err)
+	}
+	limit := int32(len(c.orders) * len(c.Reflectors) * positions)
+	for _, settings := range c.Entries {
+		for _, s := range settings {
+			if s < 0 || s >= limit {
+				return nil, fmt.Errorf("reading %s: setting %d is out of range", path, s)
+			}
+		}
+	}
+	return c, nil
+}
+
+/*
+	Cached loads the catalog from the file if it is there and covers the
+	same rotors and reflectors, otherwise it builds the catalog and saves
+	it there for next time.
+*/
+func Cached(path string, opts Options) (*Catalog, error) {
+	opts.setDefaults()
+	c, err := Load(path)
+	if err == nil && reflect.DeepEqual(c.Rotors, opts.Rotors) && reflect.DeepEqual(c.Reflectors, opts.Reflectors) {
+		return c, nil
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if c, err = Build(opts); err != nil {
+		return nil, err
+	}
+	return c, c.Save(path)
+}
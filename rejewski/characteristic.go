@@ -0,0 +1,116 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+/*
+	Package rejewski recovers the daily key of doubled indicators the way
+	Marian Rejewski did in the 1930s.
+
+	Each message began with its message key enciphered twice at the day's
+	ground setting, so the first and fourth letters of every indicator are
+	the same letter enciphered three key presses apart, and likewise the
+	second and fifth and the third and sixth. A day's indicators give the
+	three permutations AD, BE and CF that join them. The plugboard changes
+	their letters but not the lengths of their cycles, so those lengths, the
+	characteristic of the day, only depend on the wheel order and the
+	ground setting and can be looked up in a catalog of all of them.
+*/
+package rejewski
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+/*
+	Permutations returns AD, BE and CF from a day's doubled indicators of 6
+	letters each. There must be enough of them to give all 26 letters of
+	each permutation.
+*/
+func Permutations(indicators []string) ([3]enigma.Permutation, error) {
+	var ps [3]enigma.Permutation
+	var from [3][26]string // The indicator each letter came from.
+	for _, indicator := range indicators {
+		indicator = strings.ToUpper(indicator)
+		if len(indicator) != 6 || strings.IndexFunc(indicator, notLetter) >= 0 {
+			return ps, fmt.Errorf("an indicator is 6 letters, not %q", indicator)
+		}
+		for i := range ps {
+			a, b := rune(indicator[i]), rune(indicator[i+3])
+			if prev := from[i][a-'A']; prev != "" && ps[i][a-'A'] != b {
+				return ps, fmt.Errorf("indicators %s and %s disagree", prev, indicator)
+			}
+			ps[i][a-'A'], from[i][a-'A'] = b, indicator
+		}
+	}
+
+	for i, p := range ps {
+		var seen [26]bool
+		for j, c := range p {
+			if c == 0 {
+				return ps, fmt.Errorf("no indicator has %c as letter %d, need more indicators",
+					rune('A'+j), i+1)
+			}
+			if seen[c-'A'] {
+				return ps, fmt.Errorf("indicators with different letters %d both have %c as letter %d",
+					i+1, c, i+4)
+			}
+			seen[c-'A'] = true
+		}
+	}
+	return ps, nil
+}
+
+/*
+	A Characteristic is the lengths of the cycles of AD, BE and CF, longest
+	first. The cycles of each come in pairs of the same length.
+*/
+type Characteristic [3][]int
+
+// FromIndicators returns the characteristic of a day's indicators.
+func FromIndicators(indicators []string) (Characteristic, error) {
+	ps, err := Permutations(indicators)
+	if err != nil {
+		return Characteristic{}, err
+	}
+	return characteristicOf(ps), nil
+}
+
+func characteristicOf(ps [3]enigma.Permutation) Characteristic {
+	var c Characteristic
+	for i, p := range ps {
+		c[i] = p.CycleLengths()
+	}
+	return c
+}
+
+// String writes the lengths of each permutation's cycles, e.g. "13 13 | 10 10 3 3 | ...".
+func (c Characteristic) String() string {
+	parts := make([]string, len(c))
+	for i, lengths := range c {
+		s := make([]string, len(lengths))
+		for j, n := range lengths {
+			s[j] = strconv.Itoa(n)
+		}
+		parts[i] = strings.Join(s, " ")
+	}
+	return strings.Join(parts, " | ")
+}
+
+func notLetter(r rune) bool {
+	return r < 'A' || r > 'Z'
+}
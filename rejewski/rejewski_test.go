@@ -0,0 +1,129 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package rejewski
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+// The doubled indicators of a day's messages, each with a random message key.
+func indicators(t *testing.T, k enigma.Key, n int) []string {
+	r := rand.New(rand.NewSource(1))
+	var list []string
+	for i := 0; i < n; i++ {
+		m, err := k.NewMachine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := string([]rune{'A' + rune(r.Intn(26)), 'A' + rune(r.Intn(26)), 'A' + rune(r.Intn(26))})
+		list = append(list, m.Encrypt(key+key))
+	}
+	return list
+}
+
+var day = enigma.Key{Reflector: "B", Rotors: []string{"III", "I", "II"}, Positions: "KDR",
+	Plugboard: "AQ BJ CX EM HT LZ"}
+
+func TestPermutations(t *testing.T) {
+	ps, err := Permutations(indicators(t, day, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := day.NewMachine()
+	m.SetPlugboard(nil)
+	expected := characteristicAt(m)
+	if c := characteristicOf(ps); c.String() != expected.String() {
+		t.Errorf("Expected the plugboard not to change the characteristic %s, got %s", expected, c)
+	}
+	for _, lengths := range characteristicOf(ps) {
+		for i := 0; i < len(lengths); i += 2 {
+			if lengths[i] != lengths[i+1] {
+				t.Errorf("Expected the cycles to come in pairs, got %v", lengths)
+			}
+		}
+	}
+
+	if _, err := Permutations(indicators(t, day, 5)); err == nil {
+		t.Errorf("Expected too few indicators to fail")
+	}
+	for _, invalid := range [][]string{{"ABCDE"}, {"ABCDE1"}, {"ABCDEF", "AXXBXX"}, {"ABCDEF", "XBCDEF"}} {
+		if _, err := Permutations(invalid); err == nil {
+			t.Errorf("Expected %v to fail", invalid)
+		}
+	}
+}
+
+func TestCharacteristicString(t *testing.T) {
+	c := Characteristic{{13, 13}, {10, 10, 3, 3}, {1, 1}}
+	if s := c.String(); s != "13 13 | 10 10 3 3 | 1 1" {
+		t.Errorf("Expected 13 13 | 10 10 3 3 | 1 1, got %s", s)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping building a catalog in short mode.")
+	}
+
+	dir, err := ioutil.TempDir("", "rejewski")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "catalog.json")
+
+	c, err := Cached(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := FromIndicators(indicators(t, day, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := c.Lookup(ch)
+	found := false
+	for _, k := range keys {
+		found = found || k.String() == "B III-I-II AAA KDR"
+	}
+	if !found {
+		t.Errorf("Expected B III-I-II AAA KDR among %v", keys)
+	}
+	if len(keys) > 1000 {
+		t.Errorf("Expected few keys to share a characteristic, got %d", len(keys))
+	}
+
+	loaded, err := Cached(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Lookup(ch), keys) {
+		t.Errorf("Expected the saved catalog to give the same keys")
+	}
+	if _, err := Cached(path, Options{Rotors: []string{"I", "II"}}); err == nil {
+		t.Errorf("Expected a catalog of 2 rotors to fail")
+	}
+	ioutil.WriteFile(path, []byte(`{"rotors":["I","II","III"],"reflectors":["B"],"entries":{"1 1":[-1]}}`), 0644)
+	if _, err := Load(path); err == nil {
+		t.Errorf("Expected a setting out of range to fail")
+	}
+}
@@ -19,14 +19,28 @@ var LETTERS = []rune{'A', 'B', 'C', 'D', 'E', 'F', 'G', 'H', 'I', 'J', 'K',
 	'L', 'M', 'N', 'O', 'P', 'Q', 'R', 'S', 'T', 'U', 'V', 'W', 'X', 'Y', 'Z'}
 
 type Rotor struct {
-	description      string
-	turnover         int32
+	description string
+
+	// Bit n is set when the rotor to the left has just turned over because
+	// this rotor moved to position n.
+	turnovers uint32
+
 	forward, reverse map[rune]rune
 }
 
 func NewRotor(description, mapping string, turnoverLetter rune) *Rotor {
+	return NewMultiNotchRotor(description, mapping, string(turnoverLetter))
+}
+
+/*
+	NewMultiNotchRotor creates a rotor that turns over the rotor to its left at
+	each of the given letters, like the naval rotors VI-VIII.
+*/
+func NewMultiNotchRotor(description, mapping, turnoverLetters string) *Rotor {
 	r := Rotor{description: description}
-	r.turnover = (turnoverLetter - 'A') + 1
+	for _, l := range turnoverLetters {
+		r.turnovers |= 1 << uint(((l-'A')+1)%26)
+	}
 	r.forward = buildForward(mapping)
 	r.reverse = buildReverse(r.forward)
 	return &r
@@ -52,6 +66,18 @@ func Rotor5() *Rotor {
 	return NewRotor("Rotor 5, 1938", "VZBRGITYUPSDNHLXAWMJQOFECK", 'Z')
 }
 
+func Rotor6() *Rotor {
+	return NewMultiNotchRotor("Rotor 6, 1939", "JPGVOUMFYQBENHZRDKASXLICTW", "ZM")
+}
+
+func Rotor7() *Rotor {
+	return NewMultiNotchRotor("Rotor 7, 1939", "NZJHGRCXMYSWBOUFAIVLPEKQDT", "ZM")
+}
+
+func Rotor8() *Rotor {
+	return NewMultiNotchRotor("Rotor 8, 1939", "FKQHTLXOCBJSPDZRAMEWNIUYGV", "ZM")
+}
+
 func ReflectorA() *Rotor {
 	return NewRotor("Reflector A", "EJMZALYXVBWFCRQUONTSPIKHGD", 'Z')
 }
@@ -89,7 +115,15 @@ func (r *Rotor) Get(letter rune, reverse bool) rune {
 }
 
 func (r *Rotor) Turnover(position int32) bool {
-	return position == r.turnover
+	return r.turnovers&(1<<uint(position%26)) != 0
+}
+
+/*
+	Reports whether the rotor is showing one of its turnover letters, which
+	means the next key press will also step the rotor to its left.
+*/
+func (r *Rotor) atNotch(position int32) bool {
+	return r.Turnover((position + 1) % 26)
 }
 
 func (r *Rotor) String() string {
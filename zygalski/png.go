@@ -0,0 +1,66 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package zygalski
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+const (
+	cell   = 8  // The width of a square of the grid, in pixels.
+	border = 16 // Around the grid.
+	size   = 51 // Squares on each side, the real sheets repeated A-Y after A-Z so they could slide.
+)
+
+var (
+	paper = color.RGBA{0xe8, 0xe0, 0xc8, 0xff}
+	dot   = color.RGBA{0xb8, 0xb0, 0x98, 0xff} // Marks a square without a hole.
+	light = color.RGBA{0x20, 0x20, 0x20, 0xff} // What shows through a hole.
+)
+
+/*
+	WritePNG draws the sheet for the left position as a PNG image: middle
+	positions down and right positions across, with a hole wherever the
+	1st and 4th letters of an indicator can be the same.
+*/
+func (s *Sheets) WritePNG(w io.Writer, left rune) error {
+	width := 2*border + size*cell
+	img := image.NewRGBA(image.Rect(0, 0, width, width))
+	for y := 0; y < width; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, paper)
+		}
+	}
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			x0, y0 := border+col*cell, border+row*cell
+			if !s.Hole(left, rune('A'+row%26), rune('A'+col%26), 0) {
+				img.Set(x0+cell/2, y0+cell/2, dot)
+				continue
+			}
+			// Leaves a gap of paper between the holes.
+			for y := y0 + 1; y < y0+cell; y++ {
+				for x := x0 + 1; x < x0+cell; x++ {
+					img.Set(x, y, light)
+				}
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
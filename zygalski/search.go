@@ -0,0 +1,163 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package zygalski
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+// The indicator of a message: its ground setting and the doubled key.
+type Indicator struct {
+	Ground string // Sent in clear, e.g. "KDR".
+	Key    string // The 6 letters of the doubled message key.
+}
+
+/*
+	ParseIndicator reads an indicator written as its ground setting and
+	doubled key, e.g. "KDR QWEQXY".
+*/
+func ParseIndicator(s string) (Indicator, error) {
+	fields := strings.Fields(strings.ToUpper(s))
+	if len(fields) != 2 || len(fields[0]) != 3 || len(fields[1]) != 6 ||
+		strings.IndexFunc(fields[0]+fields[1], notLetter) >= 0 {
+		return Indicator{}, fmt.Errorf("expected a ground setting and 6 letters, e.g. KDR QWEQXY, got %q", s)
+	}
+	return Indicator{fields[0], fields[1]}, nil
+}
+
+// A female: the pair of letters that are the same and the ground setting.
+type female struct {
+	pair   int
+	ground [3]int
+}
+
+func females(indicators []Indicator) []female {
+	var fs []female
+	for _, in := range indicators {
+		for pair := 0; pair < 3; pair++ {
+			if in.Key[pair] == in.Key[pair+3] {
+				fs = append(fs, female{pair, [3]int{int(in.Ground[0] - 'A'),
+					int(in.Ground[1] - 'A'), int(in.Ground[2] - 'A')}})
+			}
+		}
+	}
+	return fs
+}
+
+type Options struct {
+	// The rotors to choose wheel orders from, by name. Defaults to I-V.
+	Rotors []string
+
+	// The reflectors to try, by name. Defaults to B.
+	Reflectors []string
+}
+
+func (o *Options) setDefaults() {
+	if len(o.Rotors) == 0 {
+		o.Rotors = []string{"I", "II", "III", "IV", "V"}
+	}
+	if len(o.Reflectors) == 0 {
+		o.Reflectors = []string{"B"}
+	}
+}
+
+/*
+	Search stacks the sheets of every wheel order for the females among the
+	indicators and returns a key for each aperture that stays open, with
+	the rotors and rings but no positions or plugs. The rings move every
+	message's sheet the same way, so each setting of them is one aperture.
+	A dozen females leave few apart from the right one, twenty usually only
+	that one.
+
+	If ctx is cancelled the keys found so far are returned along with
+	ctx.Err().
+*/
+func Search(ctx context.Context, indicators []Indicator, opts Options) ([]enigma.Key, error) {
+	opts.setDefaults()
+	fs := females(indicators)
+	if len(fs) == 0 {
+		return nil, errors.New("none of the indicators is a female")
+	}
+
+	orders := wheelOrders(opts.Rotors)
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("need at least 3 rotors, got %d", len(opts.Rotors))
+	}
+
+	var keys []enigma.Key
+	for _, order := range orders {
+		for _, reflector := range opts.Reflectors {
+			if ctx.Err() != nil {
+				return keys, ctx.Err()
+			}
+			s, err := NewSheets(reflector, order)
+			if err != nil {
+				return nil, err
+			}
+			for _, rings := range s.stack(fs) {
+				keys = append(keys, enigma.Key{Reflector: reflector, Rotors: order, Rings: rings})
+			}
+		}
+	}
+	return keys, nil
+}
+
+/*
+	Returns the ring settings, as letters, at which every female falls
+	on a hole: with the rings at R and the ground setting at G, the sheet
+	for left position G-R is laid with its hole for G-R over the aperture.
+*/
+func (s *Sheets) stack(fs []female) []string {
+	var open []string
+	for r1 := 0; r1 < 26; r1++ {
+		for r2 := 0; r2 < 26; r2++ {
+		rings:
+			for r3 := 0; r3 < 26; r3++ {
+				for _, f := range fs {
+					l, m, r := (f.ground[0]-r1+26)%26, (f.ground[1]-r2+26)%26, (f.ground[2]-r3+26)%26
+					if s.holes[l][m][r]&(1<<uint(f.pair)) == 0 {
+						continue rings
+					}
+				}
+				open = append(open, string([]rune{rune('A' + r1), rune('A' + r2), rune('A' + r3)}))
+			}
+		}
+	}
+	return open
+}
+
+func wheelOrders(rotors []string) [][]string {
+	var orders [][]string
+	for a := range rotors {
+		for b := range rotors {
+			for c := range rotors {
+				if a != b && a != c && b != c {
+					orders = append(orders, []string{rotors[a], rotors[b], rotors[c]})
+				}
+			}
+		}
+	}
+	return orders
+}
+
+func notLetter(r rune) bool {
+	return r < 'A' || r > 'Z'
+}
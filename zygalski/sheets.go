@@ -0,0 +1,88 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+/*
+	Package zygalski finds the wheel order and rings of a day's doubled
+	indicators with Henryk Zygalski's perforated sheets.
+
+	From late 1938 each message began with a ground setting sent in clear,
+	followed by the message key enciphered twice from it. Now and then the
+	same letter came out both times, a female, which can only happen at
+	the rotor positions where the permutations of the two key presses have
+	a letter in common. A sheet marks those positions for one left rotor
+	position with a hole in a grid of middle and right positions. Laid over
+	each other, each shifted by its message's ground setting, the sheets
+	only let light through where the rings fit every female.
+*/
+package zygalski
+
+import (
+	enigma "github.com/mww/enigma-go"
+)
+
+/*
+	Sheets holds the holes of the 26 sheets of a wheel order, one for each
+	left rotor position, by the positions of the rotor's wiring. Like the
+	real sheets they assume only the right rotor moves during an indicator.
+*/
+type Sheets struct {
+	Reflector string
+	Rotors    []string
+
+	// The bits of the pairs of letters that can be female, bit 0 for the
+	// 1st and 4th, by left, middle and right position.
+	holes [26][26][26]uint8
+}
+
+// NewSheets punches the sheets of a wheel order, left to right.
+func NewSheets(reflector string, rotors []string) (*Sheets, error) {
+	k := enigma.Key{Reflector: reflector, Rotors: rotors}
+	m, err := k.NewMachine()
+	if err != nil {
+		return nil, err
+	}
+	s := &Sheets{Reflector: reflector, Rotors: rotors}
+	var scramblers [26]enigma.Permutation
+	for l := rune(0); l < 26; l++ {
+		for mid := rune(0); mid < 26; mid++ {
+			for r := range scramblers {
+				m.SetPositions('A'+l, 'A'+mid, 'A'+rune(r))
+				scramblers[r] = m.Substitution()
+			}
+			for r := 0; r < 26; r++ {
+				for pair := 0; pair < 3; pair++ {
+					// The rotors step before each key press.
+					a, b := &scramblers[(r+pair+1)%26], &scramblers[(r+pair+4)%26]
+					for x := range a {
+						if a[x] == b[x] {
+							s.holes[l][mid][r] |= 1 << uint(pair)
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+	return s, nil
+}
+
+/*
+	Hole reports whether the sheet for the left position has a hole at the
+	middle and right positions for a female in the pair of letters, 0 for
+	the 1st and 4th, 1 for the 2nd and 5th or 2 for the 3rd and 6th.
+*/
+func (s *Sheets) Hole(left, middle, right rune, pair int) bool {
+	return s.holes[left-'A'][middle-'A'][right-'A']&(1<<uint(pair)) != 0
+}
@@ -0,0 +1,148 @@
+/*
+ 	Copyright 2012 Mark Weaver
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+package zygalski
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"math/rand"
+	"testing"
+
+	enigma "github.com/mww/enigma-go"
+)
+
+func TestSheets(t *testing.T) {
+	s, err := NewSheets("B", []string{"II", "IV", "V"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := enigma.Key{Reflector: "B", Rotors: []string{"II", "IV", "V"}}
+	m, _ := k.NewMachine()
+	holes := 0
+	// IV's notch is at J, so the middle rotor doesn't move from A.
+	for _, p := range []string{"AAA", "QAC", "ZAM", "KAT"} {
+		for pair := 0; pair < 3; pair++ {
+			m.SetPositions(rune(p[0]), rune(p[1]), rune(p[2]))
+			for i := 0; i < pair; i++ {
+				m.Step('A')
+			}
+			first := m.Permutation()
+			m.Step('A')
+			m.Step('A')
+			m.Step('A')
+			fourth := m.Permutation()
+			expected := false
+			for x := range first {
+				expected = expected || first[x] == fourth[x]
+			}
+			if h := s.Hole(rune(p[0]), rune(p[1]), rune(p[2]), pair); h != expected {
+				t.Errorf("Expected a hole at %s for pair %d to be %t", p, pair, expected)
+			} else if h {
+				holes++
+			}
+		}
+	}
+	if holes == 0 || holes == 12 {
+		t.Errorf("Expected some holes, got %d of 12", holes)
+	}
+}
+
+// Indicators of messages that don't move the middle rotor, until there are enough females.
+func indicators(t *testing.T, k enigma.Key, n int) []Indicator {
+	r := rand.New(rand.NewSource(3))
+	letters := func() string {
+		return string([]rune{'A' + rune(r.Intn(26)), 'A' + rune(r.Intn(26)), 'A' + rune(r.Intn(26))})
+	}
+	var list []Indicator
+	for len(females(list)) < n {
+		k.Positions = letters()
+		m, err := k.NewMachine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := letters()
+		in := Indicator{k.Positions, m.Encrypt(key + key)}
+		if m.Positions()[:2] == k.Positions[:2] {
+			list = append(list, in)
+		}
+	}
+	return list
+}
+
+func TestSearch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping full search in short mode.")
+	}
+
+	k := enigma.Key{Reflector: "B", Rotors: []string{"V", "II", "IV"}, Rings: "CMT",
+		Plugboard: "AQ BJ CX EM HT LZ"}
+	keys, err := Search(context.Background(), indicators(t, k, 20), Options{Rotors: []string{"II", "IV", "V"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].String() != "B V-II-IV CMT AAA" {
+		t.Errorf("Expected B V-II-IV CMT AAA, got %v", keys)
+	}
+
+	if _, err := Search(context.Background(), []Indicator{{"AAA", "ABCDEF"}}, Options{}); err == nil {
+		t.Errorf("Expected no females to fail")
+	}
+	if _, err := Search(context.Background(), indicators(t, k, 1), Options{Rotors: []string{"I", "II"}}); err == nil {
+		t.Errorf("Expected 2 rotors to fail")
+	}
+}
+
+func TestParseIndicator(t *testing.T) {
+	in, err := ParseIndicator(" kdr  QWEQXY ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in != (Indicator{"KDR", "QWEQXY"}) {
+		t.Errorf("Expected {KDR QWEQXY}, got %v", in)
+	}
+	for _, invalid := range []string{"KDR", "KD QWEQXY", "KDR QWEQX", "KDR QWE1XY", "KDR QWE QXY"} {
+		if _, err := ParseIndicator(invalid); err == nil {
+			t.Errorf("Expected %q to fail", invalid)
+		}
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	s, err := NewSheets("B", []string{"I", "II", "III"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := s.WritePNG(&buf, 'A'); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := img.Bounds().Dx(); w != 2*border+size*cell {
+		t.Errorf("Expected a width of %d, got %d", 2*border+size*cell, w)
+	}
+	for row := 0; row < 26; row++ {
+		for col := 0; col < 26; col++ {
+			x, y := border+col*cell+1, border+row*cell+1
+			if (img.At(x, y) == light) != s.Hole('A', rune('A'+row), rune('A'+col), 0) {
+				t.Fatalf("Expected the square at %c%c to show whether it is a hole", 'A'+row, 'A'+col)
+			}
+		}
+	}
+}